@@ -0,0 +1,187 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// Prefix is the url prefix of fingerprinted assets
+	Prefix = "/assets/"
+
+	// CacheControl is the Cache-Control header sent with fingerprinted assets. The content of a
+	// fingerprinted url never changes so it can be cached forever
+	CacheControl = "public, max-age=31536000, immutable"
+
+	// Default is the manifest used by the package level funcs and the page package
+	Default = New()
+)
+
+// Asset represents a fingerprinted file
+type Asset struct {
+	Path      string // path the asset is referenced by, e.g. /static/js/forms.js
+	URL       string // fingerprinted url, e.g. /assets/js/forms.3f2a9c1b04d7.js
+	Integrity string // Subresource Integrity hash of the file
+	File      string // file served for the URL
+}
+
+// Manifest maps asset paths to their fingerprinted urls
+type Manifest struct {
+	mu     sync.RWMutex
+	byPath map[string]Asset
+	byURL  map[string]Asset
+}
+
+// New creates an empty Manifest
+func New() *Manifest {
+	return &Manifest{
+		byPath: map[string]Asset{},
+		byURL:  map[string]Asset{},
+	}
+}
+
+// Add adds assets to the manifest
+func (m *Manifest) Add(assets ...Asset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, a := range assets {
+		m.byPath[a.Path] = a
+		m.byURL[a.URL] = a
+	}
+}
+
+// Lookup returns the asset referenced by path
+func (m *Manifest) Lookup(path string) (Asset, bool) {
+	m.mu.RLock()
+	a, ok := m.byPath[path]
+	m.mu.RUnlock()
+	return a, ok
+}
+
+// URL returns the fingerprinted url of path, if path is not in the manifest it is returned unchanged
+func (m *Manifest) URL(path string) string {
+	if a, ok := m.Lookup(path); ok {
+		return a.URL
+	}
+	return path
+}
+
+// HashDir fingerprints every file in dir. urlPath is the path the files are referenced by,
+// so with a urlPath of "/static/" the file dir/js/forms.js is referenced as /static/js/forms.js
+func (m *Manifest) HashDir(dir, urlPath string) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(b)
+		ext := path.Ext(rel)
+
+		m.Add(Asset{
+			Path:      path.Join("/", urlPath, rel),
+			URL:       Prefix + strings.TrimSuffix(rel, ext) + "." + hex.EncodeToString(sum[:])[:12] + ext,
+			Integrity: integrity(b),
+			File:      file,
+		})
+
+		return nil
+	})
+}
+
+// Load reads a JSON manifest mapping referenced paths to fingerprinted file names relative to dir, e.g.
+//
+//	{"/static/js/forms.js": "js/forms.3f2a9c1b04d7.js"}
+//
+// The files are read to compute their integrity hashes
+func (m *Manifest) Load(manifest, dir string) error {
+	b, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]string{}
+	if err := json.Unmarshal(b, &files); err != nil {
+		return fmt.Errorf("assets: invalid manifest %s: %v", manifest, err)
+	}
+
+	for p, name := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		m.Add(Asset{
+			Path:      path.Join("/", p),
+			URL:       Prefix + strings.TrimPrefix(name, "/"),
+			Integrity: integrity(b),
+			File:      file,
+		})
+	}
+
+	return nil
+}
+
+// Handler serves fingerprinted assets with far-future cache headers
+func (m *Manifest) Handler(ctx *gin.Context) {
+	m.mu.RLock()
+	a, ok := m.byURL[ctx.Request.URL.Path]
+	m.mu.RUnlock()
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.Header("Cache-Control", CacheControl)
+	ctx.File(a.File)
+}
+
+// HashDir fingerprints every file in dir using the Default manifest
+func HashDir(dir, urlPath string) error {
+	return Default.HashDir(dir, urlPath)
+}
+
+// Load reads a JSON manifest into the Default manifest
+func Load(manifest, dir string) error {
+	return Default.Load(manifest, dir)
+}
+
+// Lookup returns an asset from the Default manifest
+func Lookup(path string) (Asset, bool) {
+	return Default.Lookup(path)
+}
+
+// URL returns the fingerprinted url of path from the Default manifest
+func URL(path string) string {
+	return Default.URL(path)
+}
+
+func integrity(b []byte) string {
+	sum := sha512.Sum384(b)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSPConfig configures the Content-Security-Policy set by the CSP middleware
+type CSPConfig struct {
+	// Directives maps a directive name to its sources, e.g. "img-src": "'self' data:". The request's
+	// nonce is added to script-src and style-src
+	Directives map[string]string
+
+	// ReportOnly sends the policy with the Content-Security-Policy-Report-Only header so violations are
+	// reported but not blocked
+	ReportOnly bool
+
+	// ReportURI is where browsers send violation reports, see CSPReport
+	ReportURI string
+}
+
+// CSPReportLimit is the largest violation report CSPReport accepts in bytes
+var CSPReportLimit int64 = 16 << 10
+
+// DefaultCSP allows resources from the app and the Google fonts used by the standard layout
+var DefaultCSP = CSPConfig{
+	Directives: map[string]string{
+		"default-src": "'self'",
+		"script-src":  "'self'",
+		"style-src":   "'self' https://fonts.googleapis.com",
+		"font-src":    "'self' https://fonts.gstatic.com",
+		"img-src":     "'self' data:",
+		"object-src":  "'none'",
+		"base-uri":    "'self'",
+	},
+}
+
+// CSP generates a nonce for every request, stores it on the Page so it is added to the inline scripts
+// of the layout and sets the Content-Security-Policy header. It has to be added after the Page middleware
+func CSP(conf CSPConfig) gin.HandlerFunc {
+	header := "Content-Security-Policy"
+	if conf.ReportOnly {
+		header = "Content-Security-Policy-Report-Only"
+	}
+
+	return func(ctx *gin.Context) {
+		if isAsset(ctx) {
+			return
+		}
+
+		nonce, err := generateNonce()
+		if err != nil {
+			LoggerFromCtx(ctx).WithError(err).Error("csp: unable to generate nonce")
+			ctx.Next()
+			return
+		}
+
+		if _, ok := ctx.Get(PageCtxKey); ok {
+			PageFromCtx(ctx).Nonce = nonce
+		}
+
+		ctx.Header(header, conf.policy(nonce))
+		ctx.Next()
+	}
+}
+
+// CSPReport logs the violation reports sent by browsers to the CSPConfig ReportURI. Reports larger than
+// CSPReportLimit are rejected as the endpoint is public
+func CSPReport(ctx *gin.Context) {
+	if ctx.Request.ContentLength > CSPReportLimit {
+		ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, CSPReportLimit))
+	if err != nil {
+		// the reader returns the bytes up to the limit before its error
+		if int64(len(b)) >= CSPReportLimit {
+			ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	LoggerFromCtx(ctx).WithField("csp_report", string(b)).Warn("csp violation")
+	ctx.Status(http.StatusNoContent)
+}
+
+// policy builds the header value with the nonce added to the script and style sources
+func (c CSPConfig) policy(nonce string) string {
+	directives := map[string]string{}
+	for k, v := range c.Directives {
+		directives[k] = v
+	}
+	for _, k := range []string{"script-src", "style-src"} {
+		if _, ok := directives[k]; !ok {
+			directives[k] = "'self'"
+		}
+		directives[k] += " 'nonce-" + nonce + "'"
+	}
+	if len(c.ReportURI) > 0 {
+		directives["report-uri"] = c.ReportURI
+	}
+
+	names := make([]string, 0, len(directives))
+	for k := range directives {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = strings.TrimSpace(k + " " + directives[k])
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"reflect"
+	"runtime"
+
+	"github.com/edataforms/pkg/errorpages"
+	"github.com/edataforms/pkg/page"
+	"github.com/edataforms/pkg/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// CSRFSessionKey is where the user's CSRF token is stored in the session
+	CSRFSessionKey = "CSRFToken"
+
+	// CSRFHeader is the request header checked for the token when it is not posted as a form field
+	CSRFHeader = "X-CSRF-Token"
+
+	// CSRFCtxKey is where the token is stored on the request's context, e.g. for use in JSON responses
+	CSRFCtxKey = "CSRFToken"
+
+	// CSRFFailure is called when a request has a missing or invalid token
+	CSRFFailure gin.HandlerFunc = errorpages.Forbidden
+
+	// CSRFExempt returns true for requests whose token is not checked, e.g. endpoints that other sites
+	// or the browser post to without one. The CSPReport handler is exempt by default
+	CSRFExempt = func(ctx *gin.Context) bool {
+		return ctx.HandlerName() == cspReportHandler
+	}
+)
+
+// cspReportHandler is the name gin gives the CSPReport handler
+var cspReportHandler = runtime.FuncForPC(reflect.ValueOf(CSPReport).Pointer()).Name()
+
+// CSRF issues a token bound to the user's session and validates it on every request that is not a GET,
+// HEAD or OPTIONS request or is exempt, see CSRFExempt. The token is read from the page.CSRFFieldName
+// form field or the CSRFHeader. It has to be added after the Session middleware, the token is set on the
+// Page for the CSRFField template func whether it is added before or after the Page middleware
+func CSRF(ctx *gin.Context) {
+	if isAsset(ctx) {
+		return
+	}
+
+	s := SessionFromCtx(ctx)
+	token, err := csrfToken(s)
+	if err != nil {
+		LoggerFromCtx(ctx).WithError(err).Error("csrf: unable to generate token")
+		errorpages.InternalServerError(ctx)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set(CSRFCtxKey, token)
+	if v, ok := ctx.Get(PageCtxKey); ok {
+		if p, ok := v.(*page.Page); ok {
+			p.CSRFToken = token
+		}
+	}
+
+	switch ctx.Request.Method {
+	case "GET", "HEAD", "OPTIONS":
+		ctx.Next()
+		return
+	}
+
+	if CSRFExempt != nil && CSRFExempt(ctx) {
+		ctx.Next()
+		return
+	}
+
+	sent := ctx.Request.Header.Get(CSRFHeader)
+	if len(sent) == 0 {
+		sent = ctx.Request.PostFormValue(page.CSRFFieldName)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+		LoggerFromCtx(ctx).WithField("csrf_token_sent", len(sent) > 0).Warn("csrf: invalid token")
+		CSRFFailure(ctx)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Next()
+}
+
+// csrfToken returns the token stored in the session, generating one if needed
+func csrfToken(s *session.Session) (string, error) {
+	if token, ok := s.Data[CSRFSessionKey].(string); ok && len(token) > 0 {
+		return token, nil
+	}
+
+	token, err := session.GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	s.Data[CSRFSessionKey] = token
+	s.ShouldSave = true
+
+	return token, nil
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/edataforms/pkg/errorpages"
+	"github.com/edataforms/pkg/page"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Forbidden is called when the user is not allowed to open a route
+var Forbidden gin.HandlerFunc = errorpages.Forbidden
+
+// Guard only allows the request if the user may open its path using the rules of the menu's links, see
+// page.Menu.Allowed. It has to be added after the Session and Menu middleware
+func Guard(ctx *gin.Context) {
+	if isAsset(ctx) {
+		return
+	}
+
+	s := SessionFromCtx(ctx)
+	if !MenuFromCtx(ctx).Allowed(ctx.Request, s) {
+		LoggerFromCtx(ctx).Warn("guard: menu link not allowed")
+		Forbidden(ctx)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Next()
+}
+
+// RequirePermission only allows the request if the user has every permission, see page.HasPermission
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	l := page.Link{Permissions: permissions}
+	return func(ctx *gin.Context) {
+		if !l.Allowed(SessionFromCtx(ctx)) {
+			LoggerFromCtx(ctx).WithField("permissions", permissions).Warn("guard: permission denied")
+			Forbidden(ctx)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/biz/templates"
+	"github.com/edataforms/pkg/assets"
 	"github.com/edataforms/pkg/config"
 	"github.com/edataforms/pkg/defaultassets"
 	"github.com/edataforms/pkg/errorpages"
@@ -27,6 +28,7 @@ import (
 var (
 	SessionCtxKey = session.CtxKey
 	PageCtxKey    = "Page" // CtxKey represents where the page will be stored on the request's context
+	MenuCtxKey    = "PageMenu"
 )
 
 // Default adds commen middleware and routes
@@ -60,6 +62,12 @@ func Default(e *gin.Engine) {
 	robots.DontIndex(e)
 }
 
+// AssetRoutes serves the fingerprinted files of an asset manifest from the /assets/ route
+func AssetRoutes(e *gin.Engine, m *assets.Manifest) {
+	e.GET(assets.Prefix+"*filepath", m.Handler)
+	e.HEAD(assets.Prefix+"*filepath", m.Handler)
+}
+
 // DefaultPage is used to setup basic css and javascript files to the page
 var DefaultPage = &page.Page{
 	Scripts: defaultassets.Scripts,
@@ -67,7 +75,7 @@ var DefaultPage = &page.Page{
 	Header:  &page.Header{},
 }
 
-// Nav adds the nav menu based on the first role found that matches a menu set
+// Nav adds the nav menu for the user's menu type from the request's menu, see Menu
 func Nav(ctx *gin.Context) {
 	if isAsset(ctx) {
 		return
@@ -80,20 +88,62 @@ func Nav(ctx *gin.Context) {
 	s := SessionFromCtx(ctx)
 	p := PageFromCtx(ctx)
 
-	r := s.MenuType
-	if r == "super-admin" {
-		r = "admin"
-	}
-
-	n := page.GetMenu(r, ctx.Request)
+	// menu types inherit the links of their parents, see page.Menu.Inherit
+	n := MenuFromCtx(ctx).GetAllowed(s.MenuType, ctx.Request, s)
 	if n == nil {
 		return
 	}
 
-	p.Header.Nav = page.GetMenu(r, ctx.Request)
+	p.Header.Nav = n
 	p.Nav = p.Header.Nav
 }
 
+// Layout sets the layout used to render pages for the routes it is added to. The layout
+// must have been registered with page.RegisterLayout before the middleware is created, it
+// panics otherwise so an unknown layout fails when the routes are set up rather than on every request
+func Layout(layout page.Key) gin.HandlerFunc {
+	if !page.IsLayout(layout) {
+		panic(fmt.Sprintf("invalid layout: %v", layout))
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Set(page.LayoutCtxKey, layout)
+
+		// the page may have been set up before the route's layout was known
+		if v, ok := ctx.Get(PageCtxKey); ok {
+			if p, ok := v.(*page.Page); ok {
+				p.Layout = layout
+			}
+		}
+	}
+}
+
+// BreadCrumbs sets the page's breadcrumbs from the registered breadcrumb routes and the nav menu,
+// see page.ResolveBreadCrumbs. It has to be added after the Nav middleware, handlers can then use
+// Page.SetBreadCrumbTail to override the current page's label
+func BreadCrumbs(ctx *gin.Context) {
+	if isAsset(ctx) {
+		return
+	}
+	if ctx.Request.Method != "GET" {
+		ctx.Next()
+		return
+	}
+
+	p := PageFromCtx(ctx)
+	if len(p.BreadCrumbs) == 0 {
+		p.BreadCrumbs = page.ResolveBreadCrumbs(ctx.Request, p.Nav)
+	}
+}
+
+// Menu sets the menu used by the Nav and Guard middleware, so engines in the same process can
+// have different menus. Without it page.DefaultMenu is used
+func Menu(m *page.Menu) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(MenuCtxKey, m)
+	}
+}
+
 // Logger logs the http request and adds a logger to the context with information about the request
 func Logger(ctx *gin.Context) {
 	if isAsset(ctx) {
@@ -294,21 +344,25 @@ func Page(op *page.Page) func(*gin.Context) {
 			return
 		}
 
-		// only setup the Page on get requests
+		// only setup the Page on get requests, other requests set the state of the form they post
 		if ctx.Request.Method != "GET" {
+			defer page.BeginForm(SessionFromCtx(ctx), page.FormID(ctx.Request))()
 			ctx.Next()
 			return
 		}
 
-		// copy page - could be a better way
-		p := &page.Page{}
-		*p = *op
-		copy(p.Links, op.Links)
-		copy(p.Nav, op.Nav)
-		copy(p.Scripts, op.Scripts)
-		copy(p.SubNav, op.SubNav)
-		*p.Header = *op.Header
-		copy(p.Header.Nav, op.Header.Nav)
+		// the prototype is shared by every request
+		p := op.Clone()
+
+		// forms post back to the page they are rendered on by default
+		p.AddForm(page.FormID(ctx.Request))
+		p.Layout = page.LayoutFromCtx(ctx)
+		p.BaseURL = page.BaseURL(ctx.Request)
+
+		// the CSRF middleware sets the token on the page if it runs after this one
+		if token, ok := ctx.Get(CSRFCtxKey); ok {
+			p.CSRFToken, _ = token.(string)
+		}
 
 		s := SessionFromCtx(ctx)
 		p.HydrateFromSession(s)
@@ -335,6 +389,21 @@ func PageFromCtx(ctx *gin.Context) *page.Page {
 	return p
 }
 
+// MenuFromCtx returns the menu stored on the context, or page.DefaultMenu if there isn't one
+func MenuFromCtx(ctx *gin.Context) *page.Menu {
+	v, ok := ctx.Get(MenuCtxKey)
+	if !ok {
+		return &page.DefaultMenu
+	}
+
+	m, ok := v.(*page.Menu)
+	if !ok {
+		panic("invalid Menu stored on context")
+	}
+
+	return m
+}
+
 // LoggerFromCtx returns the Logger stored on a gin Context
 func LoggerFromCtx(ctx *gin.Context) *logrus.Entry {
 	v, ok := ctx.Get(log.LoggerCtxKey)
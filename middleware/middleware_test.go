@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/edataforms/pkg/page"
+	"github.com/edataforms/pkg/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPageConcurrentRequests is run with -race to check that requests get their own copy of the Page
+// prototype
+func TestPageConcurrentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	op := &page.Page{
+		Header:  &page.Header{Nav: []page.Link{{Href: "/a", Name: "A"}}},
+		Scripts: make([]string, 1, 10),
+	}
+
+	e := gin.New()
+	e.Use(func(ctx *gin.Context) {
+		ctx.Set(SessionCtxKey, session.New())
+	}, Page(op))
+	e.GET("/:n", func(ctx *gin.Context) {
+		p := PageFromCtx(ctx)
+		p.AddScript("/" + ctx.Param("n") + ".js")
+		p.Header.Nav[0].IsActive = true
+		p.AddBreadCrumb(ctx.Param("n"), "/")
+		ctx.String(http.StatusOK, strings.Join(p.Scripts, ","))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/%d", i), nil))
+
+			if want := fmt.Sprintf(",/%d.js", i); w.Body.String() != want {
+				t.Errorf("request %d has scripts %q, want %q", i, w.Body.String(), want)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(op.Scripts) != 1 || op.Header.Nav[0].IsActive || len(op.BreadCrumbs) != 0 {
+		t.Errorf("prototype changed: %+v", op)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	defer func(f gin.HandlerFunc) { CSRFFailure = f }(CSRFFailure)
+	CSRFFailure = func(ctx *gin.Context) {
+		ctx.Status(http.StatusForbidden)
+	}
+
+	e := gin.New()
+	e.Use(Logger, func(ctx *gin.Context) {
+		ctx.Set(SessionCtxKey, session.New())
+	}, CSRF, Page(&page.Page{}))
+	e.GET("/", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, PageFromCtx(ctx).CSRFToken)
+	})
+	e.POST("/", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	e.POST("/csp-report", CSPReport)
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.Len() == 0 {
+		t.Error("token not set on a page added after the CSRF middleware")
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("post without a token status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/csp-report", strings.NewReader(`{"csp-report":{}}`))
+	r.Header.Set("Content-Type", "application/csp-report")
+	e.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("csp report status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestLayoutUnregistered(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Layout did not panic on an unregistered layout")
+		}
+	}()
+
+	Layout(page.Key("unregistered"))
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/edataforms/pkg/page"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// OptionsLimit is the number of options returned by the Options endpoint if the request has no limit
+	OptionsLimit = 20
+
+	// OptionsMaxLimit is the most options the Options endpoint returns for a request
+	OptionsMaxLimit = 100
+)
+
+// option is an option returned by the Options endpoint
+type option struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// OptionRoutes serves the options of the registered option sources from page.OptionsPath. r can be a
+// route group with middleware that restricts who can read the options
+func OptionRoutes(r gin.IRouter) {
+	r.GET(page.OptionsPath+":source", Options)
+}
+
+// Options serves a page of options from the option source named by the "source" route param as JSON,
+// filtered by the "q" query param and paged with the "offset" and "limit" query params
+//
+//	{"options": [{"value": "42", "label": "Acme Ltd"}], "more": true}
+func Options(ctx *gin.Context) {
+	name := ctx.Param("source")
+	src, ok := page.GetOptionSource(name)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	offset, _ := strconv.Atoi(ctx.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	if limit <= 0 {
+		limit = OptionsLimit
+	}
+	if limit > OptionsMaxLimit {
+		limit = OptionsMaxLimit
+	}
+
+	opts, more, err := src.Options(ctx.Request, ctx.Query("q"), offset, limit)
+	if err != nil {
+		LoggerFromCtx(ctx).WithError(err).WithField("source", name).Error("options: unable to get options")
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]option, len(opts))
+	for i, op := range opts {
+		res[i].Value, res[i].Label = op.OptionValue()
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"options": res,
+		"more":    more,
+	})
+}
@@ -0,0 +1,155 @@
+package page
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edataforms/pkg/assets"
+	"github.com/edataforms/pkg/util/utilstrings"
+)
+
+var (
+	// AssetVersion is added to the urls of scripts and stylesheets that are not in the asset manifest so
+	// browsers fetch them again after a deploy, it defaults to the time the process started
+	AssetVersion = strconv.FormatInt(time.Now().Unix(), 10)
+
+	// CacheBuster returns the url a script or stylesheet that is not in the asset manifest is rendered with
+	CacheBuster = bustURL
+)
+
+// bustURL adds the AssetVersion to local urls, external urls are returned unchanged
+func bustURL(src string) string {
+	if len(src) == 0 || len(AssetVersion) == 0 || strings.Contains(src, "//") {
+		return src
+	}
+	if strings.Contains(src, "?") {
+		return src + "&v=" + AssetVersion
+	}
+	return src + "?v=" + AssetVersion
+}
+
+// Script is a script tag for a fingerprinted or external script
+type Script struct {
+	Src       string
+	Integrity string // Subresource Integrity hash, set from the asset manifest if empty
+	Defer     bool
+	Async     bool
+	Module    bool // adds type="module"
+}
+
+// Stylesheet is a link tag for a fingerprinted or external stylesheet
+type Stylesheet struct {
+	Href      string
+	Integrity string // Subresource Integrity hash, set from the asset manifest if empty
+}
+
+// AddScriptTag adds scripts with per script attributes to the page if they do not already exist. They
+// are kept in Scripts in the order they are added with AddScript, the attributes are kept in
+// AssetScripts
+func (p *Page) AddScriptTag(scripts ...Script) {
+	for _, s := range scripts {
+		p.Scripts = utilstrings.AddUnique(p.Scripts, s.Src)
+
+		exists := false
+		for _, e := range p.AssetScripts {
+			if e.Src == s.Src {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			p.AssetScripts = append(p.AssetScripts, s)
+		}
+	}
+}
+
+// AddModule adds ES module scripts to the page
+func (p *Page) AddModule(scripts ...string) {
+	for _, s := range scripts {
+		p.AddScriptTag(Script{Src: s, Module: true})
+	}
+}
+
+// AddDeferScript adds scripts that are executed after the document has been parsed
+func (p *Page) AddDeferScript(scripts ...string) {
+	for _, s := range scripts {
+		p.AddScriptTag(Script{Src: s, Defer: true})
+	}
+}
+
+// AddStylesheet adds stylesheets to the page if they do not already exist. They are kept in Links in the
+// order they are added with AddLink, the integrity hashes are kept in AssetLinks
+func (p *Page) AddStylesheet(links ...Stylesheet) {
+	for _, l := range links {
+		p.Links = utilstrings.AddUnique(p.Links, l.Href)
+
+		exists := false
+		for _, e := range p.AssetLinks {
+			if e.Href == l.Href {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			p.AssetLinks = append(p.AssetLinks, l)
+		}
+	}
+}
+
+// ScriptTags returns the page's Scripts in order with their attributes. Scripts found in the asset
+// manifest are rewritten to their fingerprinted urls when the page is rendered, so scripts set on a
+// prototype such as middleware.DefaultPage are fingerprinted too, other scripts are rewritten by the
+// CacheBuster
+func (p *Page) ScriptTags() []Script {
+	tags := make([]Script, len(p.Scripts))
+	for i, src := range p.Scripts {
+		tags[i].Src = src
+		for _, s := range p.AssetScripts {
+			if s.Src == src {
+				tags[i] = s
+				break
+			}
+		}
+
+		a, ok := assets.Lookup(src)
+		if !ok {
+			tags[i].Src = CacheBuster(src)
+			continue
+		}
+
+		tags[i].Src = a.URL
+		if len(tags[i].Integrity) == 0 {
+			tags[i].Integrity = a.Integrity
+		}
+	}
+	return tags
+}
+
+// LinkTags returns the page's Links in order with their integrity hashes. Stylesheets found in the asset
+// manifest are rewritten to their fingerprinted urls when the page is rendered, other stylesheets are
+// rewritten by the CacheBuster
+func (p *Page) LinkTags() []Stylesheet {
+	tags := make([]Stylesheet, len(p.Links))
+	for i, href := range p.Links {
+		tags[i].Href = href
+		for _, l := range p.AssetLinks {
+			if l.Href == href {
+				tags[i] = l
+				break
+			}
+		}
+
+		a, ok := assets.Lookup(href)
+		if !ok {
+			tags[i].Href = CacheBuster(href)
+			continue
+		}
+
+		tags[i].Href = a.URL
+		if len(tags[i].Integrity) == 0 {
+			tags[i].Integrity = a.Integrity
+		}
+	}
+	return tags
+}
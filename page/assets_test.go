@@ -0,0 +1,29 @@
+package page
+
+import (
+	"testing"
+
+	"github.com/edataforms/pkg/assets"
+)
+
+func TestScriptTags(t *testing.T) {
+	assets.Default.Add(assets.Asset{Path: "/static/app.js", URL: "/assets/app.1234.js", Integrity: "sha384-x"})
+
+	p := &Page{}
+	p.AddScript("/static/app.js", "/static/plain.js", "https://cdn.example.com/lib.js")
+	p.AddDeferScript("/static/later.js")
+
+	tags := p.ScriptTags()
+	if tags[0].Src != "/assets/app.1234.js" || tags[0].Integrity != "sha384-x" {
+		t.Errorf("manifest script = %+v, want its fingerprinted url and integrity", tags[0])
+	}
+	if tags[1].Src != "/static/plain.js?v="+AssetVersion {
+		t.Errorf("script src = %q, want the cache buster", tags[1].Src)
+	}
+	if tags[2].Src != "https://cdn.example.com/lib.js" {
+		t.Errorf("external script src = %q, want it unchanged", tags[2].Src)
+	}
+	if tags[3].Src != "/static/later.js?v="+AssetVersion || !tags[3].Defer {
+		t.Errorf("deferred script = %+v, want the cache buster and defer", tags[3])
+	}
+}
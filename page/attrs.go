@@ -0,0 +1,174 @@
+package page
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// dataAttr matches the names of data and aria attributes, e.g. "data-id" or "aria-describedby"
+var dataAttr = regexp.MustCompile(`^(data|aria)-[a-z0-9][-a-z0-9_.]*$`)
+
+// safeAttrs are the other attributes that can be set on a field. The attributes are rendered without
+// html/template's contextual escaping, so event handlers and the attributes that hold urls, styles or
+// markup are not allowed, nor the attributes set from the other options (id, name, type, value, class)
+var safeAttrs = map[string]bool{
+	"accept":         true,
+	"autocapitalize": true,
+	"autocomplete":   true,
+	"autofocus":      true,
+	"checked":        true,
+	"cols":           true,
+	"dir":            true,
+	"disabled":       true,
+	"enterkeyhint":   true,
+	"form":           true,
+	"hidden":         true,
+	"inputmode":      true,
+	"lang":           true,
+	"list":           true,
+	"max":            true,
+	"maxlength":      true,
+	"min":            true,
+	"minlength":      true,
+	"multiple":       true,
+	"pattern":        true,
+	"placeholder":    true,
+	"readonly":       true,
+	"required":       true,
+	"role":           true,
+	"rows":           true,
+	"size":           true,
+	"spellcheck":     true,
+	"step":           true,
+	"tabindex":       true,
+	"title":          true,
+	"translate":      true,
+	"wrap":           true,
+}
+
+// Attr sets an attribute of the field's input, e.g. "placeholder" or "data-id". The value is escaped when
+// rendered and overrides an attribute set by the field helper, such as the min of a NumberField.
+// Attributes that are not data or aria attributes or in the safe list, such as onclick, style or
+// formaction, are ignored
+func (fo *FieldOptions) Attr(name, value string) *FieldOptions {
+	if !validAttr(name) {
+		return fo
+	}
+
+	if fo.InputAttrs == nil {
+		fo.InputAttrs = map[string]string{}
+	}
+	fo.InputAttrs[name] = value
+	return fo
+}
+
+// Prop sets a boolean attribute of the field's input, e.g. "disabled", "readonly" or "autofocus"
+func (fo *FieldOptions) Prop(name string) *FieldOptions {
+	if !validAttr(name) {
+		return fo
+	}
+
+	if fo.InputProps == nil {
+		fo.InputProps = map[string]struct{}{}
+	}
+	fo.InputProps[name] = struct{}{}
+	return fo
+}
+
+// WrapAttr sets an attribute of the element that wraps the field's input and label
+func (fo *FieldOptions) WrapAttr(name, value string) *FieldOptions {
+	if !validAttr(name) {
+		return fo
+	}
+
+	if fo.WrapAttrs == nil {
+		fo.WrapAttrs = map[string]string{}
+	}
+	fo.WrapAttrs[name] = value
+	return fo
+}
+
+func validAttr(name string) bool {
+	if !safeAttrs[name] && !dataAttr.MatchString(name) {
+		logrus.WithField("attribute", name).Error("page: invalid field attribute")
+		return false
+	}
+	return true
+}
+
+// inputAttrs merges the attributes of the options over attrs
+func (fo *FieldOptions) inputAttrs(attrs map[string]string) map[string]string {
+	if fo == nil || (len(fo.InputAttrs) == 0 && len(fo.InputProps) == 0) {
+		return attrs
+	}
+
+	merged := make(map[string]string, len(attrs)+len(fo.InputAttrs)+len(fo.InputProps))
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	for k, v := range fo.InputAttrs {
+		merged[k] = v
+	}
+	for k := range fo.InputProps {
+		merged[k] = ""
+	}
+
+	return merged
+}
+
+// WithHelp sets the help text rendered below the field's input
+func (fo *FieldOptions) WithHelp(text string) *FieldOptions {
+	fo.Help = text
+	return fo
+}
+
+// clone returns a copy of the options with their own attribute maps
+func (fo *FieldOptions) clone() *FieldOptions {
+	nfo := *fo
+	nfo.InputAttrs = cloneStringMap(fo.InputAttrs)
+	nfo.WrapAttrs = cloneStringMap(fo.WrapAttrs)
+	if fo.InputProps != nil {
+		nfo.InputProps = make(map[string]struct{}, len(fo.InputProps))
+		for k := range fo.InputProps {
+			nfo.InputProps[k] = struct{}{}
+		}
+	}
+	return &nfo
+}
+
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	nm := make(map[string]string, len(m))
+	for k, v := range m {
+		nm[k] = v
+	}
+	return nm
+}
+
+// Attr is a template func that sets an attribute of a field, it is chainable with a pipeline:
+//
+//	{{ TextField .Page (LabelAndField "Email" "email" | Attr "placeholder" "name@example.com" | Attr "autocomplete" "email") "6" }}
+//
+// The template funcs return a copy of the field's options, so options shared by several fields are not changed
+func Attr(name string, value interface{}, field interface{}) *FieldOptions {
+	return convert(field).clone().Attr(name, fmt.Sprint(value))
+}
+
+// Prop is a template func that sets a boolean attribute of a field, e.g. (LabelAndField "Name" "name" | Prop "disabled")
+func Prop(name string, field interface{}) *FieldOptions {
+	return convert(field).clone().Prop(name)
+}
+
+// WrapAttr is a template func that sets an attribute of a field's wrapper, e.g. (LabelAndField "Name" "name" | WrapAttr "data-section" "contact")
+func WrapAttr(name string, value interface{}, field interface{}) *FieldOptions {
+	return convert(field).clone().WrapAttr(name, fmt.Sprint(value))
+}
+
+// Help is a template func that sets the help text of a field, e.g. (LabelAndField "Phone" "phone" | Help "Include the area code")
+func Help(text string, field interface{}) *FieldOptions {
+	return convert(field).clone().WithHelp(text)
+}
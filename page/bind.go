@@ -0,0 +1,447 @@
+package page
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edataforms/pkg/session"
+)
+
+// DateLayout is the layout used to encode and decode time.Time fields. It matches the value format of
+// a native date input
+var DateLayout = "2006-01-02"
+
+// FormTag is the struct tag used to bind struct fields to form fields
+//
+//	type Order struct {
+//		Name     string      `form:"name"`
+//		Tags     []string    `form:"tags"`     // multiple values, stored in FormMultiValues
+//		Items    []OrderItem `form:"items"`    // repeatable rows, ids stored in GroupValues
+//		Shipping *Address    `form:"shipping"` // stored under "shipping.<field>"
+//	}
+//
+//	type OrderItem struct {
+//		ID  int64 `form:"item_id,id"` // the id of the row, defaults to the row's index
+//		Qty int   `form:"qty"`        // stored under "qty:<id>"
+//	}
+//
+// Fields without a tag use the lowercased field name and fields tagged with "-" are skipped.
+// Untagged nested structs and embedded structs, or pointers to them, are flattened into the parent's
+// fields, the fields of tagged nested structs are prefixed with the tag and a "."
+const FormTag = "form"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SetFormStruct adds the values of a tagged struct to the user's session, see EncodeForm
+func SetFormStruct(s *session.Session, v interface{}) error {
+	return defaultForm(s).SetFormStruct(v)
+}
+
+// EncodeForm converts a tagged struct into form values, multi values and group values. Slices of values
+// are multi values like SetFormMultiValue, their first value is also set as the form value. Row fields
+// are stored under "<field>:<id>" keys as produced by LabelArrayField and KeyArrayID and each row also
+// sets "<group>:<id>" like SetGroupValue so it can be used with FieldGroup
+func EncodeForm(v interface{}) (values map[string]string, multi, groups map[string][]string, err error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	values = map[string]string{}
+	multi = map[string][]string{}
+	groups = map[string][]string{}
+
+	err = walkFields(rv, false, func(name string, _ bool, fv reflect.Value) error {
+		switch {
+		case isRows(fv.Type()):
+			return encodeRows(name, fv, values, groups)
+		case isScalarSlice(fv.Type()):
+			vals := make([]string, fv.Len())
+			for i := range vals {
+				vals[i] = formatValue(fv.Index(i))
+			}
+			multi[name] = vals
+			if len(vals) > 0 {
+				values[name] = vals[0]
+			}
+		case isScalar(fv.Type()):
+			values[name] = formatValue(fv)
+		default:
+			return fmt.Errorf("page: unsupported form field type %v for %q", fv.Type(), name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return values, multi, groups, nil
+}
+
+// DecodeForm parses a posted form into a tagged struct, the reverse of EncodeForm. Row fields are
+// read either from repeated field names in row order or from "<field>:<id>" names. Row bool fields
+// are set if the row's id was posted as a value of the field, which is how ArrayCheckBox submits.
+// The id field of rows added by a RowGroup in the browser is left empty
+func DecodeForm(r *http.Request, v interface{}) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	return walkFields(rv, true, func(name string, _ bool, fv reflect.Value) error {
+		switch {
+		case isRows(fv.Type()):
+			return decodeRows(r, name, fv)
+		case isScalarSlice(fv.Type()):
+			vals := r.Form[name]
+			sl := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+			for i, s := range vals {
+				if err := setValue(sl.Index(i), s); err != nil {
+					return fmt.Errorf("page: invalid value for %q: %v", name, err)
+				}
+			}
+			fv.Set(sl)
+		case isScalar(fv.Type()):
+			vals, ok := r.Form[name]
+			if !ok {
+				// unchecked checkboxes are not submitted
+				if fv.Kind() == reflect.Bool {
+					fv.SetBool(false)
+				}
+				return nil
+			}
+			if err := setValue(fv, vals[0]); err != nil {
+				return fmt.Errorf("page: invalid value for %q: %v", name, err)
+			}
+		default:
+			return fmt.Errorf("page: unsupported form field type %v for %q", fv.Type(), name)
+		}
+		return nil
+	})
+}
+
+func encodeRows(group string, sl reflect.Value, values map[string]string, groups map[string][]string) error {
+	for i := 0; i < sl.Len(); i++ {
+		row := indirect(sl.Index(i))
+
+		id := rowID(row, i)
+		groups[group] = append(groups[group], id)
+		values[group+":"+id] = id
+
+		err := walkFields(row, false, func(name string, _ bool, fv reflect.Value) error {
+			if !isScalar(fv.Type()) {
+				return fmt.Errorf("page: unsupported row field type %v for %q", fv.Type(), name)
+			}
+			values[name+":"+id] = formatValue(fv)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeRows(r *http.Request, group string, fv reflect.Value) error {
+	typ := fv.Type()
+	elem := typ.Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+
+	ids := r.Form[group]
+	if len(ids) == 0 {
+		// without posted ids the rows are numbered by the longest repeated field
+		n := 0
+		walkFields(reflect.New(elem).Elem(), true, func(name string, _ bool, _ reflect.Value) error {
+			if l := len(r.Form[name]); l > n {
+				n = l
+			}
+			return nil
+		})
+		for i := 0; i < n; i++ {
+			ids = append(ids, strconv.Itoa(i))
+		}
+	}
+
+	sl := reflect.MakeSlice(typ, len(ids), len(ids))
+	for i, id := range ids {
+		row := reflect.New(elem).Elem()
+
+		err := walkFields(row, true, func(name string, isID bool, f reflect.Value) error {
+			if !isScalar(f.Type()) {
+				return fmt.Errorf("page: unsupported row field type %v for %q", f.Type(), name)
+			}
+
+			var (
+				s  string
+				ok bool
+			)
+			switch {
+			case isID:
+				// rows added in the browser don't have an id yet
+				s, ok = id, !strings.HasPrefix(id, NewRowPrefix)
+			case f.Kind() == reflect.Bool:
+				s, ok = strconv.FormatBool(contains(r.Form[name], id)), true
+				if vals, keyed := r.Form[name+":"+id]; keyed {
+					s = vals[0]
+				}
+			default:
+				if vals, keyed := r.Form[name+":"+id]; keyed {
+					s, ok = vals[0], true
+				} else if vals := r.Form[name]; i < len(vals) {
+					s, ok = vals[i], true
+				}
+			}
+			if !ok {
+				return nil
+			}
+
+			if err := setValue(f, s); err != nil {
+				return fmt.Errorf("page: invalid value for %q: %v", name+":"+id, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if isPtr {
+			sl.Index(i).Set(row.Addr())
+		} else {
+			sl.Index(i).Set(row)
+		}
+	}
+
+	fv.Set(sl)
+	return nil
+}
+
+// rowID returns the value of the row's id field or the row's index if it doesn't have one
+func rowID(row reflect.Value, index int) string {
+	id := strconv.Itoa(index)
+	walkFields(row, false, func(_ string, isID bool, fv reflect.Value) error {
+		if isID {
+			id = formatValue(fv)
+		}
+		return nil
+	})
+	return id
+}
+
+// walkFields calls fn for every tagged field of rv, flattening nested structs. alloc sets nil pointers to
+// nested structs so their fields can be decoded, otherwise they are skipped
+func walkFields(rv reflect.Value, alloc bool, fn func(name string, isID bool, fv reflect.Value) error) error {
+	return walkStruct(rv, "", alloc, fn)
+}
+
+func walkStruct(rv reflect.Value, prefix string, alloc bool, fn func(name string, isID bool, fv reflect.Value) error) error {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+
+		tag := sf.Tag.Get(FormTag)
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if j := strings.Index(tag, ","); j != -1 {
+			name, opts = tag[:j], tag[j+1:]
+		}
+
+		fv := rv.Field(i)
+		if isNested(fv.Type()) {
+			// the exported fields of an unexported embedded struct can be set, the struct itself can't
+			if len(sf.PkgPath) > 0 && !sf.Anonymous {
+				continue
+			}
+
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					if !alloc || !fv.CanSet() {
+						continue
+					}
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+
+			nested := prefix
+			if len(name) > 0 {
+				nested += name + "."
+			}
+			if err := walkStruct(fv, nested, alloc, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(sf.PkgPath) > 0 {
+			continue
+		}
+
+		if len(name) == 0 {
+			name = strings.ToLower(sf.Name)
+		}
+
+		if err := fn(prefix+name, opts == "id", fv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, fmt.Errorf("page: expected a struct or a pointer to a struct, got %T", v)
+	}
+	return rv, nil
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.New(v.Type().Elem()).Elem()
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return t == timeType
+}
+
+// isNested checks if t is a struct, or a pointer to one, whose fields are form fields
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+func isScalarSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && isScalar(t.Elem())
+}
+
+func isRows(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	e := t.Elem()
+	if e.Kind() == reflect.Ptr {
+		e = e.Elem()
+	}
+	return e.Kind() == reflect.Struct && e != timeType
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(DateLayout)
+	}
+
+	switch v.Kind() {
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// setValue parses a form value into v. Strings are set as posted, so passwords and other whitespace
+// significant values are kept, the space around other values is ignored
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+
+	s = strings.TrimSpace(s)
+
+	if v.Type() == timeType {
+		if len(s) == 0 {
+			v.Set(reflect.Zero(timeType))
+			return nil
+		}
+		t, err := time.Parse(DateLayout, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(s != "" && s != "false" && s != "off" && s != "0")
+		return nil
+	}
+
+	if len(s) == 0 {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+
+	return nil
+}
+
+func contains(strs []string, s string) bool {
+	for _, v := range strs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package page
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func postRequest(values url.Values) *http.Request {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestDecodeFormWhitespace(t *testing.T) {
+	var login struct {
+		Password string `form:"password"`
+		Age      int    `form:"age"`
+	}
+
+	r := postRequest(url.Values{"password": {"  secret "}, "age": {" 42 "}})
+	if err := DecodeForm(r, &login); err != nil {
+		t.Fatal(err)
+	}
+
+	if login.Password != "  secret " {
+		t.Errorf("password = %q, want %q", login.Password, "  secret ")
+	}
+	if login.Age != 42 {
+		t.Errorf("age = %d, want 42", login.Age)
+	}
+}
+
+type address struct {
+	Street string `form:"street"`
+}
+
+type Audit struct {
+	Note string `form:"note"`
+}
+
+type order struct {
+	*Audit
+	Tags     []string `form:"tags"`
+	Shipping *address `form:"shipping"`
+	Billing  address  `form:"billing"`
+}
+
+func TestEncodeFormMultiValues(t *testing.T) {
+	values, multi, _, err := EncodeForm(order{Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Page{FormValues: values, FormMultiValues: multi}
+	if got := FieldValues(p, "tags"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("FieldValues(tags) = %v, want [a b]", got)
+	}
+}
+
+func TestDecodeFormNested(t *testing.T) {
+	var o order
+
+	r := postRequest(url.Values{
+		"note":            {"fragile"},
+		"shipping.street": {"1 High St"},
+		"billing.street":  {"2 Low St"},
+	})
+	if err := DecodeForm(r, &o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Audit == nil || o.Note != "fragile" {
+		t.Errorf("embedded struct not decoded: %+v", o.Audit)
+	}
+	if o.Shipping == nil || o.Shipping.Street != "1 High St" {
+		t.Errorf("nested struct pointer not decoded: %+v", o.Shipping)
+	}
+	if o.Billing.Street != "2 Low St" {
+		t.Errorf("nested struct not decoded: %+v", o.Billing)
+	}
+
+	values, _, _, err := EncodeForm(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["shipping.street"] != "1 High St" || values["note"] != "fragile" {
+		t.Errorf("nested structs not encoded: %v", values)
+	}
+}
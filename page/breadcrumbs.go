@@ -0,0 +1,275 @@
+package page
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// BreadCrumbLabel returns the label of a route's breadcrumb. params holds the values of the route's
+// path params, e.g. {"id": "42"} for the pattern "/customers/:id"
+type BreadCrumbLabel func(req *http.Request, params map[string]string) string
+
+// BreadCrumbResolver derives the breadcrumb trail of a request from registered route patterns and the menu
+type BreadCrumbResolver struct {
+	mu     sync.RWMutex
+	routes []breadCrumbRoute
+}
+
+type breadCrumbRoute struct {
+	segments []string
+	label    BreadCrumbLabel
+}
+
+// DefaultBreadCrumbs is the resolver used by the package level funcs
+var DefaultBreadCrumbs = &BreadCrumbResolver{}
+
+// Route sets the label of the breadcrumb for a gin route pattern, e.g. "/customers/new"
+func (b *BreadCrumbResolver) Route(pattern, label string) {
+	b.RouteFunc(pattern, func(*http.Request, map[string]string) string {
+		return label
+	})
+}
+
+// RouteFunc sets a func that resolves the label of the breadcrumb for a gin route pattern with params,
+// e.g. "/customers/:id" can look up the customer's name
+func (b *BreadCrumbResolver) RouteFunc(pattern string, label BreadCrumbLabel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.routes = append(b.routes, breadCrumbRoute{
+		segments: pathSegments(pattern),
+		label:    label,
+	})
+}
+
+// Resolve returns the trail for the request's path. Every leading path of the request, from "/" to the
+// full path, gets a breadcrumb if it matches a route or the href of a link in menu. The breadcrumb of
+// the current page has no link. Nil is returned if the trail would only hold a single breadcrumb
+func (b *BreadCrumbResolver) Resolve(req *http.Request, menu []Link) []BreadCrumb {
+	segments := pathSegments(req.URL.Path)
+
+	var crumbs []BreadCrumb
+	for i := 0; i <= len(segments); i++ {
+		pt := "/" + strings.Join(segments[:i], "/")
+
+		label, ok := b.label(req, segments[:i])
+		if !ok {
+			label, ok = menuLabel(menu, pt)
+		}
+		if !ok || len(label) == 0 {
+			continue
+		}
+
+		crumbs = append(crumbs, BreadCrumb{Label: label, Link: pt})
+	}
+
+	if len(crumbs) < 2 {
+		return nil
+	}
+
+	// the current page is not linked
+	if last := &crumbs[len(crumbs)-1]; last.Link == "/"+strings.Join(segments, "/") {
+		last.Link = ""
+	}
+
+	return crumbs
+}
+
+// label returns the label of the first route that matches the path segments
+func (b *BreadCrumbResolver) label(req *http.Request, segments []string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, r := range b.routes {
+		params, ok := matchRoute(r.segments, segments)
+		if ok {
+			return r.label(req, params), true
+		}
+	}
+
+	return "", false
+}
+
+// matchRoute matches path segments against the segments of a gin route pattern
+func matchRoute(pattern, segments []string) (map[string]string, bool) {
+	params := map[string]string{}
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "*") {
+			params[p[1:]] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(p, ":") {
+			params[p[1:]] = segments[i]
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// menuLabel returns the name of the link in the menu tree with the href
+func menuLabel(links []Link, href string) (string, bool) {
+	for _, l := range links {
+		if l.Href == href {
+			return l.Name, true
+		}
+		if name, ok := menuLabel(l.Links, href); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AddBreadCrumbRoute sets the label of a route's breadcrumb in the DefaultBreadCrumbs
+func AddBreadCrumbRoute(pattern, label string) {
+	DefaultBreadCrumbs.Route(pattern, label)
+}
+
+// AddBreadCrumbRouteFunc sets the label resolver of a route's breadcrumb in the DefaultBreadCrumbs
+func AddBreadCrumbRouteFunc(pattern string, label BreadCrumbLabel) {
+	DefaultBreadCrumbs.RouteFunc(pattern, label)
+}
+
+// ResolveBreadCrumbs returns the trail for the request using the DefaultBreadCrumbs
+func ResolveBreadCrumbs(req *http.Request, menu []Link) []BreadCrumb {
+	return DefaultBreadCrumbs.Resolve(req, menu)
+}
+
+// SetBreadCrumbTail replaces the label of the last breadcrumb, or adds it if there are none. It is used
+// by handlers to override the tail of a resolved trail
+func (p *Page) SetBreadCrumbTail(label string) *Page {
+	if len(p.BreadCrumbs) == 0 {
+		return p.AddBreadCrumb(label, "")
+	}
+
+	p.BreadCrumbs[len(p.BreadCrumbs)-1].Label = label
+	return p
+}
+
+var (
+	// SiteURL is the scheme and host of the site, e.g. "https://example.com". If it is set BaseURL returns
+	// it instead of the url of the request, so absolute urls don't depend on the request's headers
+	SiteURL = ""
+
+	// TrustedProxies are the IPs or CIDRs of the proxies whose X-Forwarded-Proto and X-Forwarded-Host
+	// headers are used by BaseURL, the headers of other clients are ignored
+	TrustedProxies []string
+)
+
+// BaseURL returns the scheme and host of the request, e.g. "https://example.com", or the SiteURL if it is
+// set. The forwarded headers of a TrustedProxy that terminates TLS are used for the scheme and host. An
+// empty string is returned if the host is invalid, so links are left relative
+func BaseURL(req *http.Request) string {
+	if len(SiteURL) > 0 {
+		return strings.TrimSuffix(SiteURL, "/")
+	}
+
+	scheme, host := "http", req.Host
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	if trustedProxy(req.RemoteAddr) {
+		switch proto := strings.ToLower(forwarded(req, "X-Forwarded-Proto")); proto {
+		case "http", "https":
+			scheme = proto
+		}
+		if fh := forwarded(req, "X-Forwarded-Host"); len(fh) > 0 {
+			host = fh
+		}
+	}
+
+	if !validHost(host) {
+		return ""
+	}
+
+	return scheme + "://" + host
+}
+
+// forwarded returns the value a forwarded header was set to by the closest proxy
+func forwarded(req *http.Request, header string) string {
+	return strings.TrimSpace(strings.Split(req.Header.Get(header), ",")[0])
+}
+
+// trustedProxy checks if the remote address of a request is one of the TrustedProxies
+func trustedProxy(addr string) bool {
+	if len(TrustedProxies) == 0 {
+		return false
+	}
+
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		addr = h
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, p := range TrustedProxies {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			if n.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if pip := net.ParseIP(p); pip != nil && pip.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validHost checks that a host is a host name or IP with an optional port and nothing else
+func validHost(host string) bool {
+	if len(host) == 0 || strings.ContainsAny(host, "/\\@?#% \t\r\n") {
+		return false
+	}
+
+	u, err := url.Parse("http://" + host)
+	return err == nil && u.Host == host && len(u.Hostname()) > 0
+}
+
+// BreadCrumbList returns the breadcrumbs as a schema.org BreadcrumbList for JSON-LD output. The links are
+// made absolute with the page's BaseURL as the schema requires
+func (p *Page) BreadCrumbList() map[string]interface{} {
+	items := make([]map[string]interface{}, len(p.BreadCrumbs))
+	for i, bc := range p.BreadCrumbs {
+		item := map[string]interface{}{
+			"@type":    "ListItem",
+			"position": i + 1,
+			"name":     bc.Label,
+		}
+		if len(bc.Link) > 0 {
+			item["item"] = p.absoluteURL(bc.Link)
+		}
+		items[i] = item
+	}
+
+	return map[string]interface{}{
+		"@context":        "https://schema.org",
+		"@type":           "BreadcrumbList",
+		"itemListElement": items,
+	}
+}
+
+// absoluteURL adds the page's BaseURL to a path
+func (p *Page) absoluteURL(pt string) string {
+	if !strings.HasPrefix(pt, "/") || strings.HasPrefix(pt, "//") {
+		return pt
+	}
+	return strings.TrimSuffix(p.BaseURL, "/") + pt
+}
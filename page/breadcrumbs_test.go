@@ -0,0 +1,46 @@
+package page
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBaseURL(t *testing.T) {
+	defer func(p []string) { TrustedProxies = p }(TrustedProxies)
+
+	tests := []struct {
+		name    string
+		remote  string
+		host    string
+		proto   string
+		fwdHost string
+		proxies []string
+		want    string
+	}{
+		{name: "request", host: "example.com", want: "http://example.com"},
+		{name: "untrusted headers", host: "example.com", proto: "https", fwdHost: "evil.com", want: "http://example.com"},
+		{name: "trusted proxy", remote: "10.0.0.1:1234", host: "internal", proto: "https", fwdHost: "example.com", proxies: []string{"10.0.0.0/8"}, want: "https://example.com"},
+		{name: "invalid scheme", remote: "10.0.0.1:1234", host: "example.com", proto: "javascript", proxies: []string{"10.0.0.1"}, want: "http://example.com"},
+		{name: "invalid host", host: "example.com/evil", want: ""},
+	}
+
+	for _, tt := range tests {
+		TrustedProxies = tt.proxies
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = tt.host
+		if len(tt.remote) > 0 {
+			r.RemoteAddr = tt.remote
+		}
+		if len(tt.proto) > 0 {
+			r.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		if len(tt.fwdHost) > 0 {
+			r.Header.Set("X-Forwarded-Host", tt.fwdHost)
+		}
+
+		if got := BaseURL(r); got != tt.want {
+			t.Errorf("%s: BaseURL() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package page
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/edataforms/pkg/html/htmlselect"
+)
+
+// choiceGroup is the data of the "choice-group" template, a fieldset of radios or checkboxes
+type choiceGroup struct {
+	*field
+	LegendClass string
+	Choices     []*field
+}
+
+// RadioGroup renders a radio for each option in a fieldset with the field's label as its legend. layout
+// is "inline" or "stacked", the default. The radio of the field's form value is checked
+func RadioGroup(p *Page, field interface{}, layout string, options []htmlselect.Option) template.HTML {
+	fo := convert(field)
+
+	g := newChoiceGroup(p, fo, layout)
+	for _, op := range options {
+		value, label := op.OptionValue()
+
+		f := radioField(p, choiceOptions(fo, value, label))
+		f.InputClass = f.class(ElementRadioInput) + " " + fo.CssClass
+		f.addValidation(p, fo)
+		g.add(f)
+	}
+
+	return g.render("choice-group")
+}
+
+// CheckboxGroup renders a checkbox for each option in a fieldset with the field's label as its legend.
+// layout is "inline" or "stacked", the default. The checkboxes of the field's values are checked, see
+// FieldValues
+func CheckboxGroup(p *Page, field interface{}, layout string, options []htmlselect.Option) template.HTML {
+	fo := convert(field)
+
+	values := FieldValues(p, fo.Key)
+
+	g := newChoiceGroup(p, fo, layout)
+	for _, op := range options {
+		value, label := op.OptionValue()
+
+		f := newField(p, choiceOptions(fo, value, label))
+		f.Type = "checkbox"
+		f.WrapClass = f.class(ElementCheckboxWrap)
+		f.InputClass = f.class(ElementCheckboxInput) + " " + fo.CssClass
+		f.LabelClass = f.class(ElementCheckboxLabel)
+		if contains(values, value) {
+			f.setAttr("checked", "")
+		}
+		f.Value = value
+		g.add(f)
+	}
+
+	return g.render("choice-group")
+}
+
+func newChoiceGroup(p *Page, fo *FieldOptions, layout string) *choiceGroup {
+	f := newField(p, fo)
+
+	e := ElementChoicesStacked
+	if layout == "inline" {
+		e = ElementChoicesInline
+	}
+	f.WrapClass = f.class(ElementChoices) + " " + f.class(e)
+
+	return &choiceGroup{
+		field:       f,
+		LegendClass: f.class(ElementLegend),
+	}
+}
+
+func (g *choiceGroup) render(name string) template.HTML {
+	return renderTemplate(g.theme, name, g)
+}
+
+// add adds a choice to the group. The group renders the error and help text, so the choices only keep
+// their invalid state
+func (g *choiceGroup) add(f *field) {
+	f.Error = ""
+	f.Help = ""
+	g.Choices = append(g.Choices, f)
+}
+
+// choiceOptions returns the options of a single choice of a group
+func choiceOptions(fo *FieldOptions, value, label string) *FieldOptions {
+	return &FieldOptions{
+		Label:      label,
+		Name:       fo.Name,
+		Key:        fo.Key,
+		CssClass:   fo.CssClass,
+		CssID:      strings.Replace(strings.ToLower(fo.CssID+"-"+value), " ", "-", -1),
+		InputValue: value,
+		InputAttrs: fo.InputAttrs,
+		InputProps: fo.InputProps,
+	}
+}
@@ -0,0 +1,491 @@
+package page
+
+import (
+	"bytes"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// field is the data passed to the field templates. Every field helper builds a field and renders it
+// with one of the templates below, which share the same wrapper, label and error structure and rely on
+// html/template for escaping
+type field struct {
+	ID      string
+	Name    string
+	Label   string
+	Type    string // input type
+	Value   string
+	Error   string
+	Help    string
+	Control string // "input", "textarea" or "select"
+	NoLabel bool
+	Invalid bool
+
+	InvalidClass     string
+	WrapClass        string
+	InputClass       string
+	LabelClass       string
+	HiddenLabelClass string
+	HelpClass        string
+	ErrorClass       string
+
+	Options   []fieldOption
+	OptGroups []fieldOptGroup
+	Search    bool   // adds a type-ahead input to a select
+	Remote    string // url of the option source of a select, see SelectConfig.Source
+	Nonce     string // nonce of the search script
+
+	attrs      map[string]string
+	validation map[string]string // attributes of the page's Validator, they override attrs
+	opts       *FieldOptions
+	theme      Theme
+}
+
+// fieldOption is an option of a select field
+type fieldOption struct {
+	Value    string
+	Label    string
+	Selected bool
+	Disabled bool
+	Hidden   bool
+}
+
+// newField creates a field for the options, looking up its value and error from the page
+func newField(p *Page, fo *FieldOptions) *field {
+	f := themedField(p.Theme())
+	f.opts = fo
+	f.ID = fo.CssID
+	f.Name = fo.Name
+	f.Label = fo.Label
+	f.Value = FieldValue(p, fo.Key)
+	f.Error = p.FormErrors[fo.Key]
+	f.Help = fo.Help
+	_, f.Invalid = p.FormErrors[fo.Key]
+
+	return f
+}
+
+// themedField creates an empty field with the theme's invalid and error classes
+func themedField(t Theme) *field {
+	return &field{
+		Control:          "input",
+		InvalidClass:     t.Class(ElementInvalid),
+		HiddenLabelClass: t.Class(ElementHiddenLabel),
+		HelpClass:        t.Class(ElementHelp),
+		ErrorClass:       t.Class(ElementError),
+		attrs:            map[string]string{},
+		theme:            t,
+	}
+}
+
+// class returns the classes of an element from the field's theme
+func (f *field) class(e Element) string {
+	return f.theme.Class(e)
+}
+
+// setAttr sets an attribute of the field's input, an empty value renders a boolean attribute
+func (f *field) setAttr(name, value string) {
+	f.attrs[name] = value
+}
+
+// addValidation adds the HTML5 validation attributes of the page's Validator. They override the
+// attributes set by the field helper, such as the min="0" of a NumberField, so the input enforces the
+// same rules as the server
+func (f *field) addValidation(p *Page, fo *FieldOptions) {
+	f.validation = validationAttrs(p, fo)
+}
+
+// Attrs renders the input's attributes in name order. The validation attributes and the ARIA state of
+// the field are added to the attributes set by the field helper, the attributes of the FieldOptions
+// override all of them
+func (f *field) Attrs() template.HTMLAttr {
+	attrs := make(map[string]string, len(f.attrs)+len(f.validation)+3)
+	for k, v := range f.attrs {
+		attrs[k] = v
+	}
+	for k, v := range f.validation {
+		attrs[k] = v
+	}
+
+	if f.Invalid {
+		attrs["aria-invalid"] = "true"
+	}
+	if ids := f.describedBy(); len(ids) > 0 {
+		attrs["aria-describedby"] = strings.Join(ids, " ")
+	}
+
+	attrs = f.opts.inputAttrs(attrs)
+	if _, ok := attrs["required"]; ok {
+		if _, ok := attrs["aria-required"]; !ok {
+			attrs["aria-required"] = "true"
+		}
+	}
+
+	return renderAttrs(attrs)
+}
+
+// describedBy returns the ids of the elements that describe the input, the error replaces the help text
+func (f *field) describedBy() []string {
+	if len(f.ID) == 0 {
+		return nil
+	}
+
+	switch {
+	case len(f.Error) > 0:
+		return []string{f.ErrorID()}
+	case len(f.Help) > 0:
+		return []string{f.HelpID()}
+	}
+	return nil
+}
+
+// DescribedBy returns the value of the aria-describedby attribute of an element that wraps the inputs,
+// such as the fieldset of a RadioGroup
+func (f *field) DescribedBy() string {
+	return strings.Join(f.describedBy(), " ")
+}
+
+// HelpID is the id of the field's help text, referenced by the input's aria-describedby
+func (f *field) HelpID() string {
+	return f.ID + "-help"
+}
+
+// ErrorID is the id of the field's error message, referenced by the input's aria-describedby
+func (f *field) ErrorID() string {
+	return f.ID + "-error"
+}
+
+// WrapAttrs renders the attributes of the wrapper from the FieldOptions
+func (f *field) WrapAttrs() template.HTMLAttr {
+	if f.opts == nil {
+		return ""
+	}
+	return renderAttrs(f.opts.WrapAttrs)
+}
+
+func renderAttrs(attrs map[string]string) template.HTMLAttr {
+	names := make([]string, 0, len(attrs))
+	for k := range attrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	str := ""
+	for _, k := range names {
+		if len(attrs[k]) == 0 {
+			str += " " + k
+			continue
+		}
+		str += " " + k + `="` + template.HTMLEscapeString(attrs[k]) + `"`
+	}
+
+	return template.HTMLAttr(str)
+}
+
+// render executes one of the field templates of the field's theme
+func (f *field) render(name string) template.HTML {
+	return renderTemplate(f.theme, name, f)
+}
+
+// renderTemplate executes one of the field templates of a theme
+func renderTemplate(t Theme, name string, data interface{}) template.HTML {
+	var buf bytes.Buffer
+	if err := fieldTemplatesFor(t).ExecuteTemplate(&buf, name, data); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"template": name,
+			"error":    err,
+		}).Error("page: unable to render field")
+		return ""
+	}
+
+	return template.HTML(buf.String())
+}
+
+var fieldTemplates = template.Must(template.New("fields").Parse(fieldTemplateSource))
+
+// fieldTemplateSource defines the field templates, a Theme can replace any of them
+const fieldTemplateSource = `
+{{ define "error" }}{{ if .Error }}
+		<span class="{{ .ErrorClass }}"{{ if .ID }} id="{{ .ErrorID }}"{{ end }}>{{ .Error }}</span>
+{{ end }}{{ end }}
+
+{{ define "group-error" }}{{ if .Error }}
+		<span class="{{ .ErrorClass }}" id="{{ .ID }}" tabindex="-1">{{ .Error }}</span>
+{{ end }}{{ end }}
+
+{{ define "help" }}{{ if and .Help (not .Error) }}
+		<span class="{{ .HelpClass }}"{{ if .ID }} id="{{ .HelpID }}"{{ end }}>{{ .Help }}</span>
+{{ end }}{{ end }}
+
+{{ define "label" }}
+		<label class="{{ .LabelClass }}" for="{{ .ID }}">{{ .Label }}</label>
+{{ end }}
+
+{{ define "hidden-label" }}{{ if .Label }}
+		<label class="{{ .HiddenLabelClass }}" for="{{ .ID }}">{{ .Label }}</label>
+{{ end }}{{ end }}
+
+{{ define "input" }}
+		<input class="{{ .InputClass }}" type="{{ .Type }}" id="{{ .ID }}" name="{{ .Name }}"{{ .Attrs }} value="{{ .Value }}">
+{{ end }}
+
+{{ define "textarea" }}
+		<textarea class="{{ .InputClass }}" id="{{ .ID }}" name="{{ .Name }}"{{ .Attrs }}>{{ .Value }}</textarea>
+{{ end }}
+
+{{ define "select" }}
+		<select id="{{ .ID }}" name="{{ .Name }}" class="{{ .InputClass }}"{{ .Attrs }}>
+		{{ range .Options }}{{ template "option" . }}{{ end }}
+		{{ range .OptGroups }}
+			<optgroup label="{{ .Label }}"{{ if .Disabled }} disabled{{ end }}>
+			{{ range .Options }}{{ template "option" . }}{{ end }}
+			</optgroup>
+		{{ end }}
+		</select>
+		{{ if .Search }}{{ template "select-search" . }}{{ end }}
+		{{ if .Remote }}{{ template "select-remote" . }}{{ end }}
+{{ end }}
+
+{{ define "option" }}
+			<option value="{{ .Value }}"{{ if .Selected }} selected{{ end }}{{ if .Disabled }} disabled{{ end }}{{ if .Hidden }} hidden{{ end }}>{{ .Label }}</option>
+{{ end }}
+
+{{ define "select-remote" }}
+		<input type="search" class="select-search" id="{{ .ID }}-search" aria-label="Search {{ .Label }}" aria-controls="{{ .ID }}" autocomplete="off" placeholder="Search">
+		<button type="button" class="select-more" id="{{ .ID }}-more" hidden>More</button>
+		<script{{ if .Nonce }} nonce="{{ .Nonce }}"{{ end }}>
+		(function() {
+			var sel = document.getElementById({{ .ID }});
+			var search = document.getElementById({{ .ID }} + "-search");
+			var more = document.getElementById({{ .ID }} + "-more");
+			var url = {{ .Remote }}, limit = 20, query = "", offset = 0, loaded = false, timer;
+
+			function load(append) {
+				var q = query;
+				fetch(url + "?q=" + encodeURIComponent(q) + "&offset=" + offset + "&limit=" + limit, {
+					credentials: "same-origin",
+					headers: { "Accept": "application/json" }
+				}).then(function(res) {
+					if (!res.ok) {
+						throw new Error("page: unable to load the options of " + sel.id + ": " + res.status);
+					}
+					return res.json();
+				}).then(function(data) {
+					if (q !== query) {
+						return;
+					}
+					if (!append) {
+						// the selected options are kept so the current value is still submitted
+						Array.prototype.slice.call(sel.options).forEach(function(o) {
+							if (!o.selected && !o.disabled) {
+								o.parentNode.removeChild(o);
+							}
+						});
+					}
+					data.options.forEach(function(o) {
+						if (!sel.querySelector('option[value="' + CSS.escape(o.value) + '"]')) {
+							sel.appendChild(new Option(o.label, o.value));
+						}
+					});
+					offset += data.options.length;
+					more.hidden = !data.more;
+				}).catch(function(err) {
+					// the options are requested again on the next focus
+					loaded = false;
+					console.error(err);
+				});
+			}
+
+			function init() {
+				if (!loaded) {
+					loaded = true;
+					load(false);
+				}
+			}
+
+			sel.addEventListener("focus", init);
+			search.addEventListener("focus", init);
+			search.addEventListener("input", function() {
+				clearTimeout(timer);
+				timer = setTimeout(function() {
+					query = search.value.trim();
+					offset = 0;
+					loaded = true;
+					load(false);
+				}, 250);
+			});
+			more.addEventListener("click", function() {
+				load(true);
+			});
+		}());
+		</script>
+{{ end }}
+
+{{ define "select-search" }}
+		<input type="search" class="select-search" id="{{ .ID }}-search" aria-label="Search {{ .Label }}" aria-controls="{{ .ID }}" autocomplete="off" placeholder="Search">
+		<script{{ if .Nonce }} nonce="{{ .Nonce }}"{{ end }}>
+		(function() {
+			var search = document.getElementById({{ .ID }} + "-search");
+			var sel = document.getElementById({{ .ID }});
+			var options = Array.prototype.filter.call(sel.options, function(o) {
+				return !o.disabled && !o.hidden;
+			});
+			var list = options.map(function(o, i) {
+				return { index: i, label: o.text };
+			});
+			// the fuse script is loaded after the body, so the index is created on the first search
+			var fuse = null;
+
+			search.addEventListener("input", function() {
+				if (!fuse && window.Fuse) {
+					fuse = new Fuse(list, { keys: ["label"], threshold: 0.4 });
+				}
+				var q = search.value.trim();
+				var matches = {};
+				if (q && fuse) {
+					fuse.search(q).forEach(function(r) {
+						matches[(r.item || r).index] = true;
+					});
+				}
+				options.forEach(function(o, i) {
+					o.hidden = q.length > 0 && !(fuse ? matches[i] : o.text.toLowerCase().indexOf(q.toLowerCase()) !== -1);
+				});
+
+				if (!sel.multiple && (!sel.selectedOptions.length || sel.selectedOptions[0].hidden)) {
+					var first = options.filter(function(o) { return !o.hidden; })[0];
+					if (first) {
+						first.selected = true;
+						sel.dispatchEvent(new Event("change", { bubbles: true }));
+					}
+				}
+			});
+		}());
+		</script>
+{{ end }}
+
+{{ define "control" }}
+	{{- if eq .Control "textarea" }}{{ template "textarea" . }}
+	{{- else if eq .Control "select" }}{{ template "select" . }}
+	{{- else }}{{ template "input" . }}{{ end -}}
+{{ end }}
+
+{{ define "field" }}
+	<div class="{{ if .Invalid }}{{ .InvalidClass }} {{ end }}{{ .WrapClass }}"{{ .WrapAttrs }}>
+		{{ template "control" . }}
+		{{ if .NoLabel }}{{ template "hidden-label" . }}{{ else }}{{ template "label" . }}{{ end }}
+		{{ template "help" . }}
+		{{ template "error" . }}
+	</div>
+{{ end }}
+
+{{ define "hidden" }}
+		<input type="hidden"{{ if .ID }} id="{{ .ID }}"{{ end }} name="{{ .Name }}" value="{{ .Value }}"{{ .Attrs }}>
+{{ end }}
+
+{{ define "choice" }}
+	<label class="{{ .WrapClass }}" for="{{ .ID }}"{{ .WrapAttrs }}>
+		<input type="{{ .Type }}" id="{{ .ID }}"{{ if .InputClass }} class="{{ .InputClass }}"{{ end }} name="{{ .Name }}" value="{{ .Value }}"{{ .Attrs }}>
+		{{ if not .NoLabel }}<span class="{{ .LabelClass }}">{{ .Label }}</span>{{ else if .Label }}<span class="{{ .HiddenLabelClass }}">{{ .Label }}</span>{{ end }}
+	</label>
+	{{ template "help" . }}
+	{{ template "error" . }}
+{{ end }}
+
+{{ define "repeat-row" }}
+		<div class="repeat-group__row" data-row-id="{{ .ID }}">
+			<input type="hidden" name="{{ .Group }}" value="{{ .ID }}">
+			{{ range .Fields }}{{ . }}{{ end }}
+			<div class="repeat-group__controls">
+				<button type="button" class="repeat-group__up" data-repeat="up" aria-label="Move row up">&uarr;</button>
+				<button type="button" class="repeat-group__down" data-repeat="down" aria-label="Move row down">&darr;</button>
+				<button type="button" class="repeat-group__remove" data-repeat="remove" aria-label="Remove row">&times;</button>
+			</div>
+		</div>
+{{ end }}
+
+{{ define "repeat-group" }}
+	<div class="{{ if .Invalid }}{{ .InvalidClass }} {{ end }}repeat-group" id="{{ .ID }}">
+		<div class="repeat-group__rows">
+		{{ range .Rows }}{{ template "repeat-row" . }}{{ end }}
+		</div>
+		<template class="repeat-group__blank">{{ template "repeat-row" .Blank }}</template>
+		{{ template "error" . }}
+		<button type="button" class="repeat-group__add {{ .BtnClass }}" data-repeat="add">{{ .Add }}</button>
+	</div>
+	<script{{ if .Nonce }} nonce="{{ .Nonce }}"{{ end }}>
+	(function() {
+		var group = document.getElementById({{ .ID }});
+		var rows = group.querySelector(".repeat-group__rows");
+		var blank = group.querySelector(".repeat-group__blank");
+		var min = {{ .Min }}, max = {{ .Max }}, added = 0;
+
+		function update() {
+			var n = rows.children.length;
+			group.querySelector("[data-repeat=add]").disabled = max > 0 && n >= max;
+			Array.prototype.forEach.call(rows.children, function(row, i) {
+				row.querySelector("[data-repeat=remove]").disabled = n <= min;
+				row.querySelector("[data-repeat=up]").disabled = i === 0;
+				row.querySelector("[data-repeat=down]").disabled = i === n - 1;
+			});
+		}
+
+		group.addEventListener("click", function(e) {
+			var btn = e.target.closest("[data-repeat]");
+			if (!btn || btn.closest(".repeat-group") !== group) {
+				return;
+			}
+			var row = btn.closest(".repeat-group__row");
+
+			switch (btn.getAttribute("data-repeat")) {
+			case "add":
+				var id = {{ .NewRow }} + Date.now().toString(36) + (++added);
+				rows.insertAdjacentHTML("beforeend", blank.innerHTML.split("__ID__").join(id));
+				row = rows.lastElementChild;
+				if (window.componentHandler) {
+					componentHandler.upgradeElements(row);
+				}
+				var input = row.querySelector("input:not([type=hidden]), select, textarea");
+				if (input) {
+					input.focus();
+				}
+				break;
+			case "remove":
+				rows.removeChild(row);
+				break;
+			case "up":
+				if (row.previousElementSibling) {
+					rows.insertBefore(row, row.previousElementSibling);
+				}
+				break;
+			case "down":
+				if (row.nextElementSibling) {
+					rows.insertBefore(row.nextElementSibling, row);
+				}
+				break;
+			}
+			update();
+		});
+
+		update();
+	}());
+	</script>
+{{ end }}
+
+{{ define "choice-group" }}
+	<fieldset class="{{ if .Invalid }}{{ .InvalidClass }} {{ end }}{{ .WrapClass }}" id="{{ .ID }}"{{ with .DescribedBy }} aria-describedby="{{ . }}"{{ end }}{{ .WrapAttrs }}>
+		<legend class="{{ .LegendClass }}">{{ .Label }}</legend>
+		{{ range .Choices }}{{ template "choice" . }}{{ end }}
+		{{ template "help" . }}
+		{{ template "error" . }}
+	</fieldset>
+{{ end }}
+
+{{ define "submit" }}
+	<button type="submit" class="{{ .InputClass }}">
+		{{ .Label }}
+	</button>
+{{ end }}
+`
@@ -0,0 +1,220 @@
+package page
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	describedByAttr = regexp.MustCompile(`aria-describedby="([^"]*)"`)
+	idAttr          = regexp.MustCompile(`\sid="([^"]*)"`)
+	labelFor        = regexp.MustCompile(`<label[^>]*\sfor="([^"]*)"`)
+	inputTag        = regexp.MustCompile(`<(input|select|textarea)\s[^>]*>`)
+)
+
+// ids returns the ids of the elements of the markup
+func ids(html template.HTML) map[string]bool {
+	found := map[string]bool{}
+	for _, m := range idAttr.FindAllStringSubmatch(string(html), -1) {
+		found[m[1]] = true
+	}
+	return found
+}
+
+// checkMarkup checks that every aria-describedby and label of the markup references an element of it
+func checkMarkup(t *testing.T, name string, html template.HTML) {
+	t.Helper()
+
+	found := ids(html)
+	for _, m := range describedByAttr.FindAllStringSubmatch(string(html), -1) {
+		for _, id := range strings.Fields(m[1]) {
+			if !found[id] {
+				t.Errorf("%s: aria-describedby references missing id %q:\n%s", name, id, html)
+			}
+		}
+	}
+	labels := map[string]bool{}
+	for _, m := range labelFor.FindAllStringSubmatch(string(html), -1) {
+		labels[m[1]] = true
+		if !found[m[1]] {
+			t.Errorf("%s: label for references missing id %q:\n%s", name, m[1], html)
+		}
+	}
+	for _, in := range inputTag.FindAllString(string(html), -1) {
+		m := idAttr.FindStringSubmatch(in)
+		if strings.Contains(in, `type="hidden"`) || strings.Contains(in, "aria-label=") {
+			continue
+		}
+		if m == nil || !labels[m[1]] {
+			t.Errorf("%s: input without a label: %s", name, in)
+		}
+	}
+}
+
+func errorPage() *Page {
+	return &Page{
+		FormValues: map[string]string{},
+		FormErrors: map[string]string{
+			"name":      "Name is required",
+			"active":    "Active is required",
+			"colour":    "Choose a colour",
+			"rows:2":    "Row 2 is invalid",
+			"rows":      "Choose a row",
+			"agreement": "You must agree",
+		},
+	}
+}
+
+func TestFieldMarkup(t *testing.T) {
+	p := errorPage()
+
+	fields := map[string]template.HTML{
+		"TextField":     TextField(p, &FieldOptions{Name: "name", Key: "name", CssID: "name", Label: "Name", Help: "Your full name"}, ""),
+		"TextArea":      TextAreaField(p, &FieldOptions{Name: "name", Key: "name", CssID: "name", Label: "Name"}, "3", ""),
+		"BoolCheckBox":  BoolCheckBox(p, "active", ""),
+		"ArrayCheckBox": ArrayCheckBox(p, "rows", 2),
+		"RadioField":    RadioField(p, BasicRadioInput("Red", "colour", "red")),
+		"RadioGroup":    RadioGroup(p, "colour", "", nil),
+		"NativeDate":    NativeDateField(p, "name", ""),
+		"Select":        SelectField(p, "name", nil),
+		"PhoneField":    PhoneField(p, &FieldOptions{Name: "name", Key: "name", CssID: "name", Label: "Phone", Help: "Mobile"}),
+	}
+	for name, html := range fields {
+		checkMarkup(t, name, html)
+	}
+
+	if html := PhoneField(p, "name"); !strings.Contains(string(html), "Name is required") {
+		t.Errorf("phone field error not rendered:\n%s", html)
+	}
+}
+
+func TestFieldInvalid(t *testing.T) {
+	p := errorPage()
+
+	fields := map[string]template.HTML{
+		"TextField":     TextField(p, "name", ""),
+		"BoolCheckBox":  BoolCheckBox(p, "active", ""),
+		"ArrayCheckBox": ArrayCheckBox(p, "rows", 2),
+		"RadioField":    RadioField(p, BasicRadioInput("Red", "colour", "red")),
+	}
+	for name, html := range fields {
+		inputs := inputTag.FindAllString(string(html), -1)
+		if len(inputs) == 0 {
+			t.Errorf("%s: no input:\n%s", name, html)
+		}
+		for _, in := range inputs {
+			if !strings.Contains(in, `aria-invalid="true"`) {
+				t.Errorf("%s: invalid input without aria-invalid: %s", name, in)
+			}
+		}
+	}
+}
+
+func TestChoiceError(t *testing.T) {
+	p := errorPage()
+
+	html := BoolCheckBox(p, "agreement", "")
+	if !strings.Contains(string(html), "You must agree") {
+		t.Errorf("checkbox error not rendered:\n%s", html)
+	}
+
+	// the error of a radio belongs to all of the field's radios, so it is not repeated by each radio
+	html = RadioField(p, BasicRadioInput("Red", "colour", "red"))
+	if strings.Contains(string(html), "Choose a colour") {
+		t.Errorf("radio rendered the field's error:\n%s", html)
+	}
+
+	// the rows show their own errors, the error of the rows is rendered once by GroupError
+	html = ArrayCheckBox(p, "rows", 1) + ArrayCheckBox(p, "rows", 2)
+	if strings.Contains(string(html), "Choose a row") {
+		t.Errorf("array checkbox rendered the error of the rows:\n%s", html)
+	}
+	if strings.Count(string(html), "Row 2 is invalid") != 1 {
+		t.Errorf("array checkbox did not render the row's error:\n%s", html)
+	}
+	if strings.Contains(string(ArrayCheckBox(p, "rows", 1)), "aria-invalid") {
+		t.Error("valid row is marked invalid")
+	}
+	if html := GroupError(p, "rows"); !strings.Contains(string(html), "Choose a row") {
+		t.Errorf("group error not rendered:\n%s", html)
+	}
+}
+
+func TestHiddenLabel(t *testing.T) {
+	p := errorPage()
+
+	html := NativeDateField(p, "name", "")
+	m := labelFor.FindStringSubmatch(string(html))
+	if m == nil || m[1] != "name" {
+		t.Fatalf("field without a visible label has no label for its input:\n%s", html)
+	}
+	if !strings.Contains(string(html), p.Theme().Class(ElementHiddenLabel)) {
+		t.Errorf("label is not visually hidden:\n%s", html)
+	}
+
+	html = ArrayCheckBox(p, "rows", 1)
+	if !strings.Contains(string(html), `class="`+p.Theme().Class(ElementHiddenLabel)+`">Rows 1<`) {
+		t.Errorf("array checkbox has no hidden label of its row:\n%s", html)
+	}
+
+	html = ArrayCheckBox(p, LabelArrayField("Select Jane", "rows", 3), 3)
+	if !strings.Contains(string(html), ">Select Jane<") {
+		t.Errorf("array checkbox has no label of the row options:\n%s", html)
+	}
+}
+
+func TestErrorSummaryIDs(t *testing.T) {
+	p := errorPage()
+	p.FormErrors["items"] = "At least 2 rows are required"
+	p.FormErrors["qty:1"] = "Qty is required"
+	p.FormErrors["sizes"] = "Choose a size"
+	p.GroupValues = map[string][]string{"items": {"1"}}
+	p.AddRowGroup(&RowGroup{Name: "items", Fields: []RowField{{Name: "qty", Label: "Qty"}}})
+
+	var summary bytes.Buffer
+	tmpl := template.Must(template.New("errorSummary").Parse(errorSummaryPartial))
+	if err := tmpl.Execute(&summary, map[string]interface{}{"Page": p}); err != nil {
+		t.Fatal(err)
+	}
+
+	form := TextField(p, "name", "") +
+		BoolCheckBox(p, "active", "") +
+		BoolCheckBox(p, "agreement", "") +
+		RadioField(p, BasicRadioInput("Red", "colour", "red")) +
+		RadioField(p, BasicRadioInput("Blue", "colour", "blue")) +
+		GroupError(p, "colour") +
+		ArrayCheckBox(p, "rows", 1) +
+		ArrayCheckBox(p, "rows", 2) +
+		GroupError(p, "rows") +
+		CheckboxGroup(p, "sizes", "", nil) +
+		RepeatGroup(p, "items")
+	found := ids(form)
+
+	links := regexp.MustCompile(`href="#([^"]*)"`).FindAllStringSubmatch(summary.String(), -1)
+	if len(links) != len(p.FormErrors) {
+		t.Fatalf("error summary has %d links for %d errors:\n%s", len(links), len(p.FormErrors), summary.String())
+	}
+	for _, m := range links {
+		if !found[m[1]] {
+			t.Errorf("error summary links to missing id %q", m[1])
+		}
+	}
+}
+
+func TestArrayCheckBoxChecked(t *testing.T) {
+	p := &Page{
+		FormValues:      map[string]string{},
+		GroupValues:     map[string][]string{"rows": {"1", "2"}},
+		FormMultiValues: map[string][]string{"rows": {"2"}},
+	}
+
+	if strings.Contains(string(ArrayCheckBox(p, "rows", 1)), " checked") {
+		t.Error("row is checked by the ids of the group")
+	}
+	if !strings.Contains(string(ArrayCheckBox(p, "rows", 2)), " checked") {
+		t.Error("posted row is not checked")
+	}
+}
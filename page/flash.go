@@ -0,0 +1,182 @@
+package page
+
+import (
+	"fmt"
+
+	"github.com/edataforms/pkg/session"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Flashes is the session key used to hold the queue of flash messages
+var Flashes = "Flashes"
+
+// FlashLevel represents the severity of a flash message
+type FlashLevel string
+
+// Available flash levels
+const (
+	FlashSuccess FlashLevel = "success"
+	FlashInfo    FlashLevel = "info"
+	FlashWarning FlashLevel = "warning"
+	FlashError   FlashLevel = "error"
+)
+
+// Flash is a message displayed to the user on the next page they view
+type Flash struct {
+	Level       FlashLevel
+	Message     string
+	Dismissible bool
+
+	// optional link rendered after the message
+	Link      string
+	LinkLabel string
+}
+
+// Class returns the css class for the flash's level
+func (f Flash) Class() string {
+	switch f.Level {
+	case FlashSuccess, FlashWarning, FlashError:
+		return "alert__" + string(f.Level)
+	default:
+		return "alert__info"
+	}
+}
+
+// AddFlash adds flash messages to the queue stored in the user's session
+func AddFlash(s *session.Session, flashes ...Flash) {
+	s.Data[Flashes] = append(getFlashes(s), flashes...)
+	s.ShouldSave = true
+}
+
+// AddSuccess adds a success flash message to the user's session
+func AddSuccess(s *session.Session, message string) {
+	AddFlash(s, Flash{Level: FlashSuccess, Message: message, Dismissible: true})
+}
+
+// AddInfo adds an info flash message to the user's session
+func AddInfo(s *session.Session, message string) {
+	AddFlash(s, Flash{Level: FlashInfo, Message: message, Dismissible: true})
+}
+
+// AddWarning adds a warning flash message to the user's session
+func AddWarning(s *session.Session, message string) {
+	AddFlash(s, Flash{Level: FlashWarning, Message: message})
+}
+
+// AddError adds an error flash message to the user's session
+func AddError(s *session.Session, message string) {
+	AddFlash(s, Flash{Level: FlashError, Message: message})
+}
+
+// GetFlashes gets the queued flash messages from the user's session, if any exist they are removed from the user's session.
+// Messages set with the legacy InfoMessage and ErrorMessage keys are included
+func GetFlashes(s *session.Session) []Flash {
+	flashes := getFlashes(s)
+	if _, ok := s.Data[Flashes]; ok {
+		delete(s.Data, Flashes)
+		s.ShouldSave = true
+	}
+
+	if msg := GetErrorMessage(s); len(msg) > 0 {
+		flashes = append(flashes, Flash{Level: FlashError, Message: msg})
+	}
+	if msg := GetInfoMessage(s); len(msg) > 0 {
+		flashes = append(flashes, Flash{Level: FlashInfo, Message: msg})
+	}
+
+	return flashes
+}
+
+// Messages returns the page's flash messages along with the ErrorMessage and InfoMessage set directly on the page
+func (p *Page) Messages() []Flash {
+	var flashes []Flash
+	if len(p.ErrorMessage) > 0 && !p.hasFlash(p.ErrorMessage, FlashError) {
+		flashes = append(flashes, Flash{Level: FlashError, Message: p.ErrorMessage})
+	}
+	if len(p.InfoMessage) > 0 && !p.hasFlash(p.InfoMessage, FlashInfo, FlashSuccess) {
+		flashes = append(flashes, Flash{Level: FlashInfo, Message: p.InfoMessage})
+	}
+
+	return append(flashes, p.Flashes...)
+}
+
+// setLegacyMessages sets the ErrorMessage and InfoMessage of the page from its latest error and info
+// flash messages if they are empty, so templates that use the errorMessage and infoMessage partials
+// still show the messages set with SetErrorMessage and SetInfoMessage
+func (p *Page) setLegacyMessages() {
+	errorMessage, infoMessage := len(p.ErrorMessage) == 0, len(p.InfoMessage) == 0
+	for _, f := range p.Flashes {
+		switch f.Level {
+		case FlashError:
+			if errorMessage {
+				p.ErrorMessage = f.Message
+			}
+		case FlashInfo, FlashSuccess:
+			if infoMessage {
+				p.InfoMessage = f.Message
+			}
+		}
+	}
+}
+
+// hasFlash returns true if the page has a flash message with the message and one of the levels
+func (p *Page) hasFlash(message string, levels ...FlashLevel) bool {
+	for _, f := range p.Flashes {
+		if f.Message != message {
+			continue
+		}
+		for _, l := range levels {
+			if f.Level == l {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddFlash adds flash messages to the page
+func (p *Page) AddFlash(flashes ...Flash) *Page {
+	p.Flashes = append(p.Flashes, flashes...)
+	return p
+}
+
+// getFlashes reads the queue without removing it. Sessions that have been decoded from storage
+// hold the queue as []interface{} of map[string]interface{}
+func getFlashes(s *session.Session) []Flash {
+	v, ok := s.Data[Flashes]
+	if !ok {
+		return nil
+	}
+
+	switch t := v.(type) {
+	case []Flash:
+		return t
+	case []interface{}:
+		flashes := make([]Flash, 0, len(t))
+		for _, i := range t {
+			m, ok := i.(map[string]interface{})
+			if !ok {
+				logrus.WithField("type", fmt.Sprintf("%T", i)).Error("page: invalid Flash stored in session")
+				continue
+			}
+
+			f := Flash{}
+			f.Level = FlashLevel(mapString(m, "Level"))
+			f.Message = mapString(m, "Message")
+			f.Link = mapString(m, "Link")
+			f.LinkLabel = mapString(m, "LinkLabel")
+			f.Dismissible, _ = m["Dismissible"].(bool)
+			flashes = append(flashes, f)
+		}
+		return flashes
+	default:
+		logrus.WithField("type", fmt.Sprintf("%T", v)).Error("page: invalid Flashes stored in session")
+		return nil
+	}
+}
+
+func mapString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
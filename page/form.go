@@ -0,0 +1,455 @@
+package page
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/edataforms/pkg/session"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// FormState scopes the form values, errors and group values stored in the user's session to a single
+// form, so two forms or two tabs don't clobber each other's redisplayed values and errors. The package
+// level Set* and Get* funcs use the form posted in the current request, see BeginForm, and the unscoped
+// form with an empty id outside of one.
+//
+// The state of a scoped form is restored by the next page that adds the form with AddForm. The form
+// stored last is also restored by the next page if the page's own forms have no state, so a form that
+// redirects to a page with a different path is still redisplayed. Otherwise the state is removed from
+// the session after that request so stale errors don't resurface later
+type FormState struct {
+	s  *session.Session
+	id string
+}
+
+var (
+	// FormScope is the session key used to hold the id of the form posted in the current request
+	FormScope = "FormScope"
+
+	// FormStates is the session key used to hold the number of requests the state of each scoped form
+	// has been stored for
+	FormStates = "FormStates"
+
+	// LastForm is the session key used to hold the id of the scoped form whose state was stored last, it
+	// is restored by the next page
+	LastForm = "LastForm"
+)
+
+// formScope is the id of the form posted in the current request, it has its own type so a session
+// decoded from storage never holds one
+type formScope string
+
+// Form returns the state of the form identified by id, see FormID
+func Form(s *session.Session, id string) *FormState {
+	return &FormState{s: s, id: id}
+}
+
+// RequestForm returns the state of the form that was posted to r
+func RequestForm(s *session.Session, r *http.Request) *FormState {
+	return Form(s, FormID(r))
+}
+
+// FormID returns the identifier of a form from the request it was posted to. Forms are identified by
+// their action path
+func FormID(r *http.Request) string {
+	return r.URL.Path
+}
+
+// BeginForm makes the package level Set* and Get* funcs use the form identified by id for the rest of
+// the request. The returned func ends the form's scope and must be called before the session is saved.
+// The Page middleware calls it with the FormID of every request that isn't a GET
+func BeginForm(s *session.Session, id string) func() {
+	s.Data[FormScope] = formScope(id)
+	return func() {
+		delete(s.Data, FormScope)
+	}
+}
+
+// defaultForm returns the form used by the package level funcs
+func defaultForm(s *session.Session) *FormState {
+	id, _ := s.Data[FormScope].(formScope)
+	return Form(s, string(id))
+}
+
+// ID returns the form's identifier
+func (f *FormState) ID() string {
+	return f.id
+}
+
+// key returns the session key for the form
+func (f *FormState) key(k string) string {
+	if len(f.id) == 0 {
+		return k
+	}
+	return k + ":" + f.id
+}
+
+// save marks the session to be saved and restarts the expiry of a scoped form's state
+func (f *FormState) save() {
+	f.s.ShouldSave = true
+	if len(f.id) == 0 {
+		return
+	}
+
+	ages := formStates(f.s)
+	ages[f.id] = 0
+	setFormStates(f.s, ages)
+	f.s.Data[LastForm] = f.id
+}
+
+// clear removes the form's state from the user's session
+func (f *FormState) clear() {
+	for _, k := range []string{FormErrors, FormValues, GroupValues, FormMultiValues} {
+		delete(f.s.Data, f.key(k))
+	}
+	f.s.ShouldSave = true
+}
+
+// SetErrors sets an error message and form errors on to the user's session
+func (f *FormState) SetErrors(message string, errs map[string]string) {
+	if len(message) > 0 {
+		AddError(f.s, message)
+	}
+	f.SetFormErrors(errs)
+}
+
+// SetFormError sets a single key/value form error
+func (f *FormState) SetFormError(key, value string) {
+	f.SetFormErrors(map[string]string{key: value})
+}
+
+// SetFormErrors adds form errors to the user's session
+func (f *FormState) SetFormErrors(errs map[string]string) {
+	f.s.Data[f.key(FormErrors)] = merge(f.stored(FormErrors), errs)
+	f.save()
+}
+
+// SetFormValues adds form values to the session
+func (f *FormState) SetFormValues(values map[string]string) {
+	f.s.Data[f.key(FormValues)] = merge(f.stored(FormValues), values)
+	f.save()
+}
+
+// SetFormValue sets a single key/value form value
+func (f *FormState) SetFormValue(key, value interface{}) {
+	f.SetFormValues(map[string]string{fmt.Sprint(key): fmt.Sprint(value)})
+}
+
+// SetFormArrayValue sets a single key/value form value
+func (f *FormState) SetFormArrayValue(key, value, id interface{}) {
+	f.SetFormValues(map[string]string{fmt.Sprintf("%v:%v", key, id): fmt.Sprint(value)})
+}
+
+// SetFormArrayError sets a single key/value form error
+func (f *FormState) SetFormArrayError(key, value, id interface{}) {
+	f.SetFormErrors(map[string]string{fmt.Sprintf("%v:%v", key, id): fmt.Sprint(value)})
+}
+
+// SetGroupValue is used in conjunction with the FieldGroup template function to group
+// related fields in an array
+func (f *FormState) SetGroupValue(key string, id interface{}) {
+	str := fmt.Sprint(id)
+	f.SetFormValue(key+":"+str, str)
+}
+
+// SetGroup is used to save an ordered list of keys that can be looped to look up other keys belonging
+// to the same group
+func (f *FormState) SetGroup(key string, id interface{}) {
+	m := sliceMap(GroupValues, f.s.Data[f.key(GroupValues)])
+	if m == nil {
+		m = map[string][]string{}
+	}
+	m[key] = append(m[key], fmt.Sprint(id))
+
+	f.s.Data[f.key(GroupValues)] = m
+	f.save()
+}
+
+// setGroupIDs replaces the ids of a group, so setting the same rows again doesn't repeat them
+func (f *FormState) setGroupIDs(key string, ids []string) {
+	m := sliceMap(GroupValues, f.s.Data[f.key(GroupValues)])
+	if m == nil {
+		m = map[string][]string{}
+	}
+	m[key] = append([]string{}, ids...)
+
+	f.s.Data[f.key(GroupValues)] = m
+	f.save()
+}
+
+// SetFormMultiValue sets all the values of a field that can have more than one value, e.g. the
+// selected options of a multi-select. Its first value is also set as the field's form value
+func (f *FormState) SetFormMultiValue(key string, values ...string) {
+	m := sliceMap(FormMultiValues, f.s.Data[f.key(FormMultiValues)])
+	if m == nil {
+		m = map[string][]string{}
+	}
+	m[key] = append([]string{}, values...)
+
+	f.s.Data[f.key(FormMultiValues)] = m
+	f.save()
+
+	if len(values) > 0 {
+		f.SetFormValue(key, values[0])
+	}
+}
+
+// SetFormURLValues adds the listed fields of posted form values to the session so a form can be
+// redisplayed, e.g. SetFormURLValues(r.PostForm, "name", "email", "roles"). A field's first value is set as
+// its form value and all its values are kept for FieldValues. Only the listed fields are stored so
+// passwords, card numbers and other fields that must not be kept in the session are never saved by a
+// field added to the form later, the CSRF token is never stored
+func (f *FormState) SetFormURLValues(values url.Values, fields ...string) {
+	single := map[string]string{}
+	m := sliceMap(FormMultiValues, f.s.Data[f.key(FormMultiValues)])
+	if m == nil {
+		m = map[string][]string{}
+	}
+
+	for _, k := range fields {
+		vs := values[k]
+		if len(vs) == 0 || k == CSRFFieldName {
+			continue
+		}
+		single[k] = vs[0]
+		m[k] = append([]string{}, vs...)
+	}
+
+	f.s.Data[f.key(FormMultiValues)] = m
+	f.SetFormValues(single)
+}
+
+// SetFormStruct adds the values of a tagged struct to the user's session, see EncodeForm
+func (f *FormState) SetFormStruct(v interface{}) error {
+	values, multi, groups, err := EncodeForm(v)
+	if err != nil {
+		return err
+	}
+
+	f.SetFormValues(values)
+	for k, vs := range multi {
+		f.SetFormMultiValue(k, vs...)
+	}
+	for k, ids := range groups {
+		f.setGroupIDs(k, ids)
+	}
+
+	return nil
+}
+
+// GetGroupValues gets the group values stored in the user's session, if any exist they are removed from the user's session
+func (f *FormState) GetGroupValues() map[string][]string {
+	v, ok := f.s.Data[f.key(GroupValues)]
+	if !ok {
+		return nil
+	}
+
+	// remove group values from session data
+	delete(f.s.Data, f.key(GroupValues))
+	f.s.ShouldSave = true
+
+	return sliceMap(GroupValues, v)
+}
+
+// GetFormMultiValues gets the multi-valued fields stored in the user's session, if any exist they are removed from the user's session
+func (f *FormState) GetFormMultiValues() map[string][]string {
+	v, ok := f.s.Data[f.key(FormMultiValues)]
+	if !ok {
+		return nil
+	}
+
+	delete(f.s.Data, f.key(FormMultiValues))
+	f.s.ShouldSave = true
+
+	return sliceMap(FormMultiValues, v)
+}
+
+// GetFormValues gets the form values stored in the user's session, if any exist they are removed from the user's session
+func (f *FormState) GetFormValues() map[string]string {
+	return f.take(FormValues)
+}
+
+// GetFormErrors gets the user's form errors from the session, if any exist they are removed from the user's session
+func (f *FormState) GetFormErrors() map[string]string {
+	return f.take(FormErrors)
+}
+
+// take returns a string map stored in the session and removes it
+func (f *FormState) take(key string) map[string]string {
+	if _, ok := f.s.Data[f.key(key)]; !ok {
+		return nil
+	}
+
+	m := f.stored(key)
+
+	delete(f.s.Data, f.key(key))
+	f.s.ShouldSave = true
+
+	return m
+}
+
+// stored returns a string map stored in the session without removing it
+func (f *FormState) stored(key string) map[string]string {
+	v, ok := f.s.Data[f.key(key)]
+	if !ok {
+		return nil
+	}
+
+	switch t := v.(type) {
+	case map[string]string:
+		return t
+	case map[string]interface{}:
+		return msiTomss(t)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"type": fmt.Sprintf("%T", v),
+		}).Errorf("page: invalid %s stored in session", key)
+		return nil
+	}
+}
+
+// sliceMap converts group values or multi values stored in the session. Sessions that have been decoded
+// from storage hold them as map[string]interface{} of []interface{}
+func sliceMap(key string, v interface{}) map[string][]string {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case map[string][]string:
+		return t
+	case map[string]interface{}:
+		m := map[string][]string{}
+		for k, v := range t {
+			i, ok := v.([]interface{})
+			if !ok {
+				logrus.WithFields(logrus.Fields{
+					"type": fmt.Sprintf("%T", v),
+				}).Errorf("page: invalid %s", key)
+				return nil
+			}
+			for _, s := range i {
+				m[k] = append(m[k], fmt.Sprint(s))
+			}
+		}
+		return m
+	default:
+		logrus.WithFields(logrus.Fields{
+			"type": fmt.Sprintf("%T", v),
+		}).Errorf("page: invalid %s stored in session", key)
+		return nil
+	}
+}
+
+// AddForm adds the id of a form rendered on the page, e.g. the action path of a form that posts to
+// another page. Its state is restored by HydrateFromSession, or straight away if the page has already
+// been hydrated, so handlers can add forms after the Page middleware
+func (p *Page) AddForm(ids ...string) *Page {
+	for _, id := range ids {
+		if contains(p.Forms, id) {
+			continue
+		}
+		p.Forms = append(p.Forms, id)
+		if p.session != nil {
+			p.HydrateForm(p.session, id)
+		}
+	}
+	return p
+}
+
+// HydrateForm restores the values, multi values, errors and group values of a single form from the user's session
+func (p *Page) HydrateForm(s *session.Session, id string) {
+	p.hydrateForm(s, id)
+}
+
+// hydrateForm restores the state of a form and returns true if the form had state
+func (p *Page) hydrateForm(s *session.Session, id string) bool {
+	f := Form(s, id)
+	stored := false
+	for _, k := range []string{FormErrors, FormValues, GroupValues, FormMultiValues} {
+		if _, ok := s.Data[f.key(k)]; ok {
+			stored = true
+		}
+	}
+
+	if len(id) > 0 {
+		if ages := formStates(s); hasKey(ages, id) {
+			delete(ages, id)
+			setFormStates(s, ages)
+		}
+	}
+
+	p.FormErrors = merge(p.FormErrors, f.GetFormErrors())
+	p.FormValues = merge(p.FormValues, f.GetFormValues())
+
+	if p.GroupValues == nil {
+		p.GroupValues = map[string][]string{}
+	}
+	for k, v := range f.GetGroupValues() {
+		p.GroupValues[k] = v
+	}
+
+	if p.FormMultiValues == nil {
+		p.FormMultiValues = map[string][]string{}
+	}
+	for k, v := range f.GetFormMultiValues() {
+		p.FormMultiValues[k] = v
+	}
+
+	return stored
+}
+
+// expireForms removes the state of the scoped forms that haven't been restored by the request before
+// this one and counts the request for the others. Forms restored by this request are already removed
+func expireForms(s *session.Session) {
+	ages := formStates(s)
+	if len(ages) == 0 {
+		return
+	}
+
+	for id, age := range ages {
+		if age > 0 {
+			Form(s, id).clear()
+			delete(ages, id)
+			continue
+		}
+		ages[id] = age + 1
+	}
+
+	setFormStates(s, ages)
+	s.ShouldSave = true
+}
+
+// formStates returns the number of requests the state of each scoped form has been stored for. Sessions
+// that have been decoded from storage hold the numbers as float64
+func formStates(s *session.Session) map[string]int {
+	ages := map[string]int{}
+	switch t := s.Data[FormStates].(type) {
+	case nil:
+	case map[string]int:
+		for k, v := range t {
+			ages[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range t {
+			n, _ := v.(float64)
+			ages[k] = int(n)
+		}
+	default:
+		logrus.WithField("type", fmt.Sprintf("%T", t)).Errorf("page: invalid %s stored in session", FormStates)
+	}
+	return ages
+}
+
+func setFormStates(s *session.Session, ages map[string]int) {
+	if len(ages) == 0 {
+		delete(s.Data, FormStates)
+		return
+	}
+	s.Data[FormStates] = ages
+}
+
+func hasKey(m map[string]int, k string) bool {
+	_, ok := m[k]
+	return ok
+}
@@ -0,0 +1,54 @@
+package page
+
+import (
+	"testing"
+
+	"github.com/edataforms/pkg/session"
+)
+
+func TestFormsAreScoped(t *testing.T) {
+	s := &session.Session{Data: map[string]interface{}{}}
+
+	end := BeginForm(s, "/a")
+	SetFormErrors(s, map[string]string{"name": "A"})
+	end()
+
+	end = BeginForm(s, "/b")
+	SetFormErrors(s, map[string]string{"name": "B"})
+	end()
+
+	a := &Page{}
+	a.AddForm("/a")
+	a.HydrateFromSession(s)
+	if a.FormErrors["name"] != "A" {
+		t.Errorf("errors of /a = %v, want A", a.FormErrors)
+	}
+
+	// the form stored last is restored by a page with a different path if its own forms have no state
+	s = &session.Session{Data: map[string]interface{}{}}
+	end = BeginForm(s, "/b")
+	SetFormErrors(s, map[string]string{"name": "B"})
+	end()
+
+	c := &Page{}
+	c.AddForm("/c")
+	c.HydrateFromSession(s)
+	if c.FormErrors["name"] != "B" {
+		t.Errorf("errors after redirect = %v, want B", c.FormErrors)
+	}
+}
+
+func TestSetRowsTwice(t *testing.T) {
+	s := &session.Session{Data: map[string]interface{}{}}
+	rows := []Row{{ID: "1", Values: map[string]string{"qty": "2"}}, {ID: "2"}}
+
+	f := Form(s, "")
+	f.SetRows("items", rows)
+	f.SetRows("items", rows)
+
+	p := &Page{}
+	p.HydrateFromSession(s)
+	if got := p.GroupValues["items"]; len(got) != 2 {
+		t.Errorf("rows = %v, want [1 2]", got)
+	}
+}
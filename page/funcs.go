@@ -5,7 +5,6 @@ import (
 	"html/template"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/biz/templates"
 	"github.com/edataforms/pkg/html/htmlselect"
@@ -16,9 +15,11 @@ func funcs() {
 	templates.AddFunc("IsValid", IsValid)
 	templates.AddFunc("FieldError", FieldError)
 	templates.AddFunc("ArrayFieldError", ArrayFieldError)
+	templates.AddFunc("GroupError", GroupError)
 	templates.AddFunc("TextField", TextField)
 	templates.AddFunc("RequiredTextField", RequiredTextField)
 	templates.AddFunc("HiddenField", HiddenField)
+	templates.AddFunc("CSRFField", CSRFField)
 	templates.AddFunc("PositiveNumberField", PositiveNumberField)
 	templates.AddFunc("NumberField", NumberField)
 	templates.AddFunc("NumberFieldMinMax", NumberFieldMinMax)
@@ -28,6 +29,17 @@ func funcs() {
 	templates.AddFunc("SelectField", SelectField)
 	templates.AddFunc("SelectField4Col", SelectField4Col)
 	templates.AddFunc("MultiSelectField", MultiSelectField)
+	templates.AddFunc("Select", Select)
+	templates.AddFunc("SelectConfig", NewSelectConfig)
+	templates.AddFunc("SelectWidth", SelectWidth)
+	templates.AddFunc("SelectPlaceholder", SelectPlaceholder)
+	templates.AddFunc("SelectMultiple", SelectMultiple)
+	templates.AddFunc("SelectSearch", SelectSearch)
+	templates.AddFunc("SelectSource", SelectSource)
+	templates.AddFunc("RemoteSelect", RemoteSelect)
+	templates.AddFunc("OptionGroup", AddOptionGroup)
+	templates.AddFunc("DisableOption", DisableOption)
+	templates.AddFunc("HideOption", HideOption)
 	templates.AddFunc("SelectFieldWithDefault", SelectFieldWithDefault)
 	templates.AddFunc("DateField", DateField)
 	templates.AddFunc("NativeDateField", NativeDateField)
@@ -40,6 +52,7 @@ func funcs() {
 	templates.AddFunc("FormGroupValues", FormGroupValues)
 	templates.AddFunc("ArrayFieldValue", ArrayFieldValue)
 	templates.AddFunc("FieldValue", FieldValue)
+	templates.AddFunc("FieldValues", FieldValues)
 	templates.AddFunc("IntSelectField", IntSelectField)
 	templates.AddFunc("Options", htmlselect.Options)
 	templates.AddFunc("StringSelectField", StringSelectField)
@@ -48,58 +61,55 @@ func funcs() {
 	templates.AddFunc("Join", Join)
 	templates.AddFunc("LabelNameKey", LabelNameKey)
 	templates.AddFunc("FieldOrderByDate", FieldOrderByDate)
+	templates.AddFunc("FieldOrderBy", FieldOrderBy)
+	templates.AddFunc("OrderKey", NewOrderKey)
 	templates.AddFunc("RadioField", RadioField)
+	templates.AddFunc("RadioFieldStyle", RadioFieldStyle)
+	templates.AddFunc("RadioGroup", RadioGroup)
+	templates.AddFunc("CheckboxGroup", CheckboxGroup)
 	templates.AddFunc("BasicRadioInput", BasicRadioInput)
 	templates.AddFunc("RadioInputClass", RadioInputClass)
 	templates.AddFunc("BoolValue", BoolValue)
 	templates.AddFunc("CssClass", CssClass)
+	templates.AddFunc("TableClass", TableClass)
 	templates.AddFunc("PhoneField", PhoneField)
 	templates.AddFunc("KeyArrayID", KeyArrayID)
 	templates.AddFunc("KeyNameLabel", KeyNameLabel)
 	templates.AddFunc("NameValue", NameValue)
+	templates.AddFunc("Attr", Attr)
+	templates.AddFunc("Prop", Prop)
+	templates.AddFunc("WrapAttr", WrapAttr)
+	templates.AddFunc("Help", Help)
+	templates.AddFunc("RepeatGroup", RepeatGroup)
 }
 
+// PhoneField renders a tel input for the phone input script with a label that is only read by screen
+// readers, as the script renders the country of the number in front of the input
 func PhoneField(p *Page, field interface{}) template.HTML {
 	fo := convert(field)
 
-	value := escapeField(p, fo.Key)
+	f := newField(p, fo)
+	f.Type = "tel"
+	f.NoLabel = true
+	f.WrapClass = f.class(ElementPlainTextWrap)
+	f.InputClass = "phone-input " + fo.CssClass
+	f.addValidation(p, fo)
 
-	return template.HTML(`
-		<input class="phone-input ` + fo.CssClass + `" type="tel" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-	`)
+	return f.render("field")
 }
 
+// CssClass returns the classes of a table element from the theme of the package Layout, see Theme.Table
 func CssClass(typ, class string) string {
-	switch typ {
-	case "report":
-		switch class {
-		case "table":
-			return " report-tbl "
-		case "td-non-numeric":
-			fallthrough
-		case "th-non-numeric":
-			return " report-tbl__text "
-		default:
-			return ""
-		}
-	case "default":
-		fallthrough
-	default:
-		switch class {
-		case "table":
-			return " edf-table mdl-data-table mdl-js-data-table "
-		case "td-non-numeric":
-			fallthrough
-		case "th-non-numeric":
-			return " mdl-data-table__cell--non-numeric "
-		default:
-			return ""
-		}
-	}
+	return LayoutTheme("").Table(typ, class)
+}
+
+// TableClass returns the classes of a table element from the theme of the page's layout
+func TableClass(p *Page, typ, class string) string {
+	return p.Theme().Table(typ, class)
 }
 
 func BoolValue(p *Page, field string) bool {
-	return escapeField(p, field) == "true"
+	return FieldValue(p, field) == "true"
 }
 
 func RadioInputClass(label, name, value, class string) *FieldOptions {
@@ -137,21 +147,23 @@ func BasicRadioInput(label, name, value string) *FieldOptions {
 	}
 }
 
+// RadioField renders a radio with the default style, see RadioFieldStyle
 func RadioField(p *Page, field interface{}) template.HTML {
-	fo := convert(field)
-	value := escapeField(p, fo.Key)
+	return RadioFieldStyle(p, RadioStyleDefault, field)
+}
 
-	checked := ""
-	if fo.InputValue == value {
-		checked = "checked"
+// radioField creates a radio input that is checked if the form value matches the input's value
+func radioField(p *Page, fo *FieldOptions) *field {
+	f := newField(p, fo)
+	f.Type = "radio"
+	f.WrapClass = f.class(ElementRadioWrap)
+	f.LabelClass = f.class(ElementRadioLabel)
+	if fo.InputValue == f.Value {
+		f.setAttr("checked", "")
 	}
+	f.Value = fo.InputValue
 
-	return template.HTML(`
-	<label class="mdl-radio mdl-js-radio mdl-js-ripple-effect" for="` + fo.CssID + `">
-		<input type="radio" id="` + fo.CssID + `" class="mdl-radio__button ` + fo.CssClass + `" name="` + fo.Name + `" value="` + fo.InputValue + `"` + checked + ` />
-		<span class="mdl-radio__label">` + fo.Label + `</span>
-	</label>
-	`)
+	return f
 }
 
 func KeyArrayID(name string, id interface{}) *FieldOptions {
@@ -167,16 +179,24 @@ func KeyArrayID(name string, id interface{}) *FieldOptions {
 func HiddenField(p *Page, field interface{}) template.HTML {
 	fo := convert(field)
 
-	value := ""
+	f := newField(p, fo)
 	if len(fo.Value) > 0 {
-		value = fo.Value
-	} else {
-		value = escapeField(p, fo.Key)
+		f.Value = fo.Value
 	}
 
-	return template.HTML(`
-		<input type="hidden" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-	`)
+	return f.render("hidden")
+}
+
+// CSRFFieldName is the name of the form field that holds the CSRF token
+var CSRFFieldName = "csrf_token"
+
+// CSRFField is used to add the page's CSRF token to a form
+func CSRFField(p *Page) template.HTML {
+	f := themedField(p.Theme())
+	f.Name = CSRFFieldName
+	f.Value = p.CSRFToken
+
+	return f.render("hidden")
 }
 
 // Join is used to concat parts together with a given separator
@@ -229,6 +249,11 @@ type FieldOptions struct {
 	InputValue string // used for default values and radio/checkbox values
 	Value      string
 	Default    string
+
+	InputAttrs map[string]string   // extra attributes of the input, see Attr
+	InputProps map[string]struct{} // boolean attributes of the input, see Prop
+	WrapAttrs  map[string]string   // attributes of the element that wraps the input and label, see WrapAttr
+	Help       string              // text rendered below the input, it is replaced by the field's error
 }
 
 // IntSelectField is used to generate a select box with ints between the start and end parameters
@@ -258,58 +283,20 @@ func FormGroupValues(p *Page, group string) []string {
 	return p.GroupValues[group]
 }
 
-type dateItem struct {
-	time  time.Time
-	index string
-}
-
-type dateOrder []*dateItem
-
-func (d dateOrder) Len() int           { return len(d) }
-func (d dateOrder) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
-func (d dateOrder) Less(i, j int) bool { return d[i].time.Before(d[j].time) }
-
-type dateOrderDesc struct{ dateOrder }
-
-func (d dateOrderDesc) Less(i, j int) bool { return d.dateOrder[i].time.After(d.dateOrder[j].time) }
-
+// FieldOrderByDate returns the ids of the FieldGroup of a group sorted by a date field, see FieldOrderBy.
+// Dates that can't be parsed are placed first when sorting "asc" and last when sorting "desc"
 func FieldOrderByDate(p *Page, group, sortField, layout, dir string) []string {
-	ids := FieldGroup(p, group)
-	if len(ids) == 0 {
-		return ids
-	}
-
-	dir = strings.ToLower(dir)
-
-	ds := make([]*dateItem, len(ids))
-
-	for i, id := range ids {
-		ds[i] = &dateItem{index: id}
-
-		s := escapeField(p, fmt.Sprintf("%s:%s", sortField, id))
-
-		d, err := time.Parse(layout, s)
-		if err != nil {
-			continue
-		}
-		ds[i].time = d
-	}
+	desc := strings.ToLower(dir) == "desc"
 
-	switch dir {
-	case "desc":
-		sort.Sort(dateOrderDesc{ds})
-	case "asc":
-		fallthrough
-	default:
-		sort.Sort(dateOrder(ds))
-	}
-
-	sorted := make([]string, len(ds))
-	for i, t := range ds {
-		sorted[i] = t.index
-	}
+	ids := FieldGroup(p, group)
+	sort.Strings(ids)
 
-	return sorted
+	return orderIDs(p, ids, []OrderKey{{
+		Field:      sortField,
+		Type:       DateOrder(layout),
+		Desc:       desc,
+		EmptyFirst: !desc,
+	}})
 }
 
 // ValueExists checks if a form field exists and has a value
@@ -326,89 +313,98 @@ func ValueExists(p *Page, field interface{}) bool {
 // BoolCheckBox is used to create a single checkbox with no value - the server will have to check for "on" or "off"
 func BoolCheckBox(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
-	width = treatWidth(width)
 
-	checked := ""
+	f := newField(p, fo)
+	f.Type = "checkbox"
+	f.WrapClass = f.class(ElementCheckboxWrap)
+	f.InputClass = f.class(ElementCheckboxInput) + " " + fo.CssClass
+	f.LabelClass = f.class(ElementCheckboxLabel)
 	if v, ok := p.FormValues[fo.Key]; ok && v != "false" && v != "off" {
-		checked = " checked "
+		f.setAttr("checked", "")
 	}
+	f.Value = "on"
 
-	return template.HTML(`
-	<label class="mdl-checkbox mdl-js-checkbox mdl-js-ripple-effect" for="` + fo.CssID + `">
-		<input ` + checked + ` value="on" type="checkbox" id="` + fo.CssID + `" name="` + fo.Name + `" class="mdl-checkbox__input ` + fo.CssClass + `">
-		<span class="mdl-checkbox__label">` + fo.Label + `</span>
-	</label>
-	`)
+	return f.render("choice")
 }
 
-// ArrayCheckBox is used to create a checkbox without a label and uses the id to lookup the value in Page.FormValues
+// ArrayCheckBox is used to create a checkbox without a label and uses the id to lookup the value in Page.FormValues,
+// or in the checked ids of Page.FormMultiValues. The checkbox shows the row's error, keyed by "<name>:<id>", the
+// error of the rows is rendered once with GroupError. The label read by screen readers is the field's label with
+// the row's id, or the label of row options such as LabelArrayField
 func ArrayCheckBox(p *Page, field interface{}, idi interface{}) template.HTML {
 	fo := convert(field)
 
 	id := fmt.Sprint(idi)
-	id = template.HTMLEscapeString(id)
-	lookup := fmt.Sprintf("%v:%v", fo.Name, id)
+	key := fo.Name + ":" + id
 
-	checked := ""
-	if v, ok := p.FormValues[lookup]; ok && v != "false" && v != "off" {
-		checked = " checked "
+	f := newField(p, fo)
+	f.ID = fo.Name + "-" + id
+	f.Error = p.FormErrors[key]
+	_, f.Invalid = p.FormErrors[key]
+	if fo.Key != key {
+		f.Label = strings.TrimSpace(fo.Label + " " + id)
+	}
+	f.Type = "checkbox"
+	f.NoLabel = true
+	f.WrapClass = f.class(ElementArrayCheckWrap)
+	f.InputClass = fo.CssClass + "-checkbox " + f.class(ElementCheckboxInput)
+	if v, ok := p.FormValues[key]; ok && v != "false" && v != "off" {
+		f.setAttr("checked", "")
+	} else if contains(p.FormMultiValues[fo.Name], id) {
+		// the checkboxes are posted as the ids of the checked rows, they are only read from
+		// FormMultiValues as the GroupValues of the same name are the ids of every row
+		f.setAttr("checked", "")
 	}
+	f.Value = id
 
-	return template.HTML(`
-	<label class="mdl-checkbox mdl-js-checkbox" for="` + fo.Name + `-` + id + `">
-		<input ` + checked + ` type="checkbox" id="` + fo.Name + `-` + id + `" name="` + fo.Name + `" value="` + id + `" class="` + fo.CssClass + `-checkbox mdl-checkbox__input">
-	</label>
-	`)
+	return f.render("choice")
 }
 
 // SubmitButton is a template function used to create a submit button for a form
 func SubmitButton(name string) template.HTML {
-	return template.HTML(`
-	<button type="submit" class="mdl-button mdl-js-button mdl-button--raised mdl-button--accent">
-		` + template.HTMLEscapeString(strings.ToUpper(name)) + `
-	</button>
-	`)
+	f := themedField(LayoutTheme(""))
+	f.Label = strings.ToUpper(name)
+	f.InputClass = f.class(ElementButton)
+
+	return f.render("submit")
 }
 
 // DateField is used to add a date picker to a text field.
 func DateField(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
-
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
+	f := textField(p, fo, width)
+	f.InputClass = "date-picker " + f.InputClass
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label ` + width + `">
-		<input class="date-picker mdl-textfield__input ` + fo.CssClass + `" type="text" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 func NativeDateField(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
-
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
+	f := textField(p, fo, width)
+	f.Type = "date"
+	f.NoLabel = true
+	f.WrapClass = f.class(ElementPlainTextWrap) + f.theme.Width(width)
 
 	// prevents a user from selecting a date from the year 1
-	if value == "0001-01-01" {
-		value = ""
+	if f.Value == "0001-01-01" {
+		f.Value = ""
 	}
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield ` + width + `">
-		<input class="mdl-textfield__input ` + fo.CssClass + `" type="date" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
+}
+
+// textField creates a text input rendered in a textfield wrapper with a floating label
+func textField(p *Page, fo *FieldOptions, width string) *field {
+	f := newField(p, fo)
+	f.Type = "text"
+	f.WrapClass = f.class(ElementTextWrap) + f.theme.Width(width)
+	f.InputClass = f.class(ElementTextInput) + " " + fo.CssClass
+	f.LabelClass = f.class(ElementTextLabel)
+	f.addValidation(p, fo)
+
+	return f
 }
 
 // TextField is a template function that is used to render an HTML text input and label.
@@ -416,23 +412,12 @@ func NativeDateField(p *Page, field interface{}, width string) template.HTML {
 func TextField(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
-
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
-
-	if len(value) == 0 {
-		value = fo.Default
+	f := textField(p, fo, width)
+	if len(f.Value) == 0 {
+		f.Value = fo.Default
 	}
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<input class="mdl-textfield__input ` + fo.CssClass + `" type="text" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 // TextField is a template function that is used to render an HTML text input and label.
@@ -440,73 +425,41 @@ func TextField(p *Page, field interface{}, width string) template.HTML {
 func RequiredTextField(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
+	f := textField(p, fo, width)
+	f.setAttr("required", "")
 
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
-
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<input required class="mdl-textfield__input ` + fo.CssClass + `" type="text" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 func PositiveNumberField(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
-
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
+	f := textField(p, fo, width)
+	f.Type = "number"
+	f.setAttr("min", "1")
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<input class="mdl-textfield__input ` + fo.CssClass + `" type="number" min="1" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 func NumberField(p *Page, field interface{}, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
+	f := textField(p, fo, width)
+	f.Type = "number"
+	f.setAttr("min", "0")
 
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
-
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<input class="mdl-textfield__input ` + fo.CssClass + `" type="number" min="0" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 func NumberFieldMinMax(p *Page, field interface{}, min, max int64, width string) template.HTML {
 	fo := convert(field)
 
-	width = treatWidth(width)
-
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
+	f := textField(p, fo, width)
+	f.Type = "number"
+	f.setAttr("min", fmt.Sprint(min))
+	f.setAttr("max", fmt.Sprint(max))
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<input class="mdl-textfield__input ` + fo.CssClass + `" type="number" min="` + fmt.Sprint(min) + `" max="` + fmt.Sprint(max) + `" id="` + fo.CssID + `" name="` + fo.Name + `" value="` + value + `">
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 // TextAreaField is a template function that is used to render an HTML text input and label.
@@ -514,20 +467,16 @@ func NumberFieldMinMax(p *Page, field interface{}, min, max int64, width string)
 func TextAreaField(p *Page, field interface{}, rows string, width string) template.HTML {
 	fo := convert(field)
 
-	rows = template.HTMLEscapeString(rows)
-	width = treatWidth(width)
+	return textAreaField(p, fo, rows, width).render("field")
+}
 
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
+// textAreaField creates a textarea rendered in a textfield wrapper with a floating label
+func textAreaField(p *Page, fo *FieldOptions, rows, width string) *field {
+	f := textField(p, fo, width)
+	f.Control = "textarea"
+	f.setAttr("rows", rows)
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<textarea class="mdl-textfield__input ` + fo.CssClass + `" type="text" id="` + fo.CssID + `" name="` + fo.Name + `" rows="` + rows + `">` + value + `</textarea>
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f
 }
 
 // TextAreaField is a template function that is used to render an HTML text input and label.
@@ -535,20 +484,10 @@ func TextAreaField(p *Page, field interface{}, rows string, width string) templa
 func RequiredTextAreaField(p *Page, field interface{}, rows string, width string) template.HTML {
 	fo := convert(field)
 
-	rows = template.HTMLEscapeString(rows)
-	width = treatWidth(width)
-
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
+	f := textAreaField(p, fo, rows, width)
+	f.setAttr("required", "")
 
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<textarea required class="mdl-textfield__input ` + fo.CssClass + `" type="text" id="` + fo.CssID + `" name="` + fo.Name + `" rows="` + rows + `">` + value + `</textarea>
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 // TextAreaFieldReadOnly is a template function that is used to render an HTML text input and label.
@@ -556,170 +495,48 @@ func RequiredTextAreaField(p *Page, field interface{}, rows string, width string
 func TextAreaFieldReadOnly(p *Page, field interface{}, rows string, width string) template.HTML {
 	fo := convert(field)
 
-	rows = template.HTMLEscapeString(rows)
-	width = treatWidth(width)
+	f := textAreaField(p, fo, rows, width)
+	f.setAttr("readonly", "")
 
-	is := IsValid(p, fo.Key)
-	fe := string(FieldError(p, fo.Key))
-	value := escapeField(p, fo.Key)
-
-	return template.HTML(`
-	<div class="` + is + `mdl-textfield mdl-js-textfield mdl-textfield--floating-label` + width + `">
-		<textarea readonly class="mdl-textfield__input ` + fo.CssClass + `" type="text" id="` + fo.CssID + `" name="` + fo.Name + `" rows="` + rows + `">` + value + `</textarea>
-		<label class="mdl-textfield__label" for="` + fo.CssID + `">` + fo.Label + `</label>
-	` + fe + `
-	</div>
-	`)
+	return f.render("field")
 }
 
 // SelectField is used to create a select field
 func SelectField(p *Page, field interface{}, options []htmlselect.Option) template.HTML {
-	fo := convert(field)
-
-	id := template.HTMLEscapeString(p.FormValues[fo.Key])
-
-	fe := string(FieldError(p, fo.Key))
-	is := IsValid(p, fo.Key)
-
-	slct := `<select id="` + fo.CssID + `" name="` + fo.Name + `" class="mdl-select__input ` + fo.CssClass + `">`
-	if len(id) == 0 {
-		slct += `<option value="0" selected disabled>` + fo.Label + "</option>"
-	} else {
-		slct += `<option value="0" disabled>` + fo.Label + "</option>"
-	}
-
-	for _, op := range options {
-		value, label := op.OptionValue()
-		value = template.HTMLEscapeString(value)
-		label = template.HTMLEscapeString(label)
-		selected := ""
-		if value == id {
-			selected = " selected"
-		}
-		slct += `<option value="` + value + `"` + selected + `>` + label + "</option>"
-	}
-	slct += "</select>"
-
-	return template.HTML(`
-			<div class="mdl-select mdl-js-select mdl-select--floating-label ` + is + ` mdl-cell mdl-cell--12-col">
-				` + slct + `
-				` + fe + `
-			</div>
-	`)
+	return Select(p, field, selectPreset(field, &SelectConfig{Options: options}))
 }
 
 // SelectField4Col is used to create a select field
 func SelectField4Col(p *Page, field interface{}, options []htmlselect.Option) template.HTML {
-	fo := convert(field)
-
-	id := template.HTMLEscapeString(p.FormValues[fo.Key])
-
-	fe := string(FieldError(p, fo.Key))
-	is := IsValid(p, fo.Key)
-
-	slct := `<select id="` + fo.CssID + `" name="` + fo.Name + `" class="mdl-select__input ` + fo.CssClass + `">`
-	if len(id) == 0 {
-		slct += `<option value="0" selected>` + fo.Label + "</option>"
-	} else {
-		slct += `<option value="0">` + fo.Label + "</option>"
-	}
-
-	for _, op := range options {
-		value, label := op.OptionValue()
-		value = template.HTMLEscapeString(value)
-		label = template.HTMLEscapeString(label)
-		selected := ""
-		if value == id {
-			selected = " selected"
-		}
-		slct += `<option value="` + value + `"` + selected + `>` + label + "</option>"
-	}
-	slct += "</select>"
-
-	return template.HTML(`
-			<div class="mdl-select mdl-js-select mdl-select--floating-label ` + is + ` mdl-cell mdl-cell--4-col">
-				` + slct + `
-				` + fe + `
-			</div>
-	`)
+	return Select(p, field, selectPreset(field, &SelectConfig{
+		Width:           "4",
+		PlaceholderMode: PlaceholderSelectable,
+		Options:         options,
+	}))
 }
 
 // MultiSelectField is used to create a multi-select field
 func MultiSelectField(p *Page, field interface{}, options []htmlselect.Option) template.HTML {
-	fo := convert(field)
-
-	vals := p.GroupValues[fo.Key]
-
-	fe := string(FieldError(p, fo.Key))
-	is := IsValid(p, fo.Key)
-
-	slct := `<select multiple id="` + fo.CssID + `" name="` + fo.Name + `" class="mdl-select__input ` + fo.CssClass + `">`
-	if len(vals) == 0 {
-		slct += `<option value="0" selected>` + fo.Label + "</option>"
-	} else {
-		slct += `<option value="0">` + fo.Label + "</option>"
-	}
-
-	for _, op := range options {
-		value, label := op.OptionValue()
-		value = template.HTMLEscapeString(value)
-		label = template.HTMLEscapeString(label)
-		selected := ""
-		for _, v := range vals {
-			if value == v {
-				selected = " selected"
-				break
-			}
-		}
-		slct += `<option value="` + value + `"` + selected + `>` + label + "</option>"
-	}
-	slct += "</select>"
-
-	return template.HTML(`
-		<div class="mdl-select mdl-js-select mdl-select--floating-label ` + is + ` mdl-cell mdl-cell--12-col">
-			` + slct + `
-			` + fe + `
-		</div>
-	`)
+	return Select(p, field, selectPreset(field, &SelectConfig{
+		Multiple:        true,
+		PlaceholderMode: PlaceholderSelectable,
+		Options:         options,
+	}))
 }
 
 func SelectFieldWithDefault(p *Page, field interface{}, defaultValue, defaultLabel interface{}, options []htmlselect.Option) template.HTML {
-	fo := convert(field)
-
-	id := template.HTMLEscapeString(p.FormValues[fo.Key])
-
-	fe := string(FieldError(p, fo.Key))
-	is := IsValid(p, fo.Key)
-
-	slct := `<select id="` + fo.CssID + `" name="` + fo.Name + `" class="mdl-select__input ` + fo.CssClass + `">`
-	if len(id) == 0 {
-		slct += `<option value="` + fmt.Sprint(defaultValue) + `" selected>` + fmt.Sprint(defaultLabel) + `</option>`
-	}
-
-	for _, op := range options {
-		value, label := op.OptionValue()
-		value = template.HTMLEscapeString(value)
-		label = template.HTMLEscapeString(label)
-		selected := ""
-		if value == id {
-			selected = " selected"
-		}
-		slct += `<option value="` + value + `"` + selected + `>` + label + "</option>"
-	}
-	slct += "</select>"
-
-	return template.HTML(`
-			<div class="mdl-select mdl-js-select mdl-select--floating-label ` + is + ` mdl-cell mdl-cell--12-col">
-				` + slct + `
-				` + fe + `
-			</div>
-	`)
+	return Select(p, field, &SelectConfig{
+		Placeholder:      fmt.Sprint(defaultLabel),
+		PlaceholderValue: fmt.Sprint(defaultValue),
+		PlaceholderMode:  PlaceholderIfEmpty,
+		Options:          options,
+	})
 }
 
 // IsValid returns the is invalid class name if the field has an error
 func IsValid(p *Page, key string) string {
 	if _, ok := p.FormErrors[key]; ok {
-		return "is-invalid "
+		return p.Theme().Class(ElementInvalid) + " "
 	}
 	return ""
 }
@@ -743,6 +560,22 @@ func FieldValue(p *Page, key string) string {
 	return v
 }
 
+// FieldValues returns all the values of a field that can have more than one value, such as a multi-select
+// or checkboxes that share a name. The values are read from FormMultiValues, falling back to the
+// field's GroupValues and then to its single form value
+func FieldValues(p *Page, key string) []string {
+	if v, ok := p.FormMultiValues[key]; ok {
+		return v
+	}
+	if v, ok := p.GroupValues[key]; ok {
+		return v
+	}
+	if v := FieldValue(p, key); len(v) > 0 {
+		return []string{v}
+	}
+	return nil
+}
+
 // FieldError returns the error message HTML if the field has an error message
 func FieldError(p *Page, key string) template.HTML {
 	// TODO(james): create a helper type to fetch from a map[string]interface{}
@@ -751,43 +584,38 @@ func FieldError(p *Page, key string) template.HTML {
 		return ""
 	}
 
-	return template.HTML(`
-		<span class="mdl-textfield__error">` + template.HTMLEscapeString(e) + `</span>
-	`)
-}
+	f := themedField(p.Theme())
+	f.ID = strings.Replace(key, ":", "-", -1)
+	f.Error = e
 
-func ArrayFieldError(p *Page, field string, id interface{}) template.HTML {
-	return FieldError(p, fmt.Sprintf("%s:%v", field, id))
+	return f.render("error")
 }
 
-func title(s string) string {
-	return template.HTMLEscapeString(strings.Title(strings.Replace(s, "-", " ", -1)))
-}
-
-func treatWidth(width string) string {
-	width = template.HTMLEscapeString(width)
-	if width == "mdl-cell" {
-		return " " + width + " "
-	}
-	if len(width) > 0 {
-		return " mdl-cell mdl-cell--" + width + "-col "
+// GroupError renders the error of a field made of several inputs, such as the RadioFields or ArrayCheckBoxes of
+// a name, once for all of its inputs. The error has the id the errorSummary links to
+func GroupError(p *Page, key string) template.HTML {
+	e, ok := p.FormErrors[key]
+	if !ok {
+		return ""
 	}
 
-	return ""
+	f := themedField(p.Theme())
+	f.ID = strings.Replace(key, ":", "-", -1)
+	f.Error = e
+
+	return f.render("group-error")
 }
 
-//escapeField returns an HTML escaped field value, if there is one
-func escapeField(p *Page, key string) string {
-	v, ok := p.FormValues[key]
-	if !ok {
-		return ""
-	}
+func ArrayFieldError(p *Page, field string, id interface{}) template.HTML {
+	return FieldError(p, fmt.Sprintf("%s:%v", field, id))
+}
 
-	return template.HTMLEscapeString(v)
+func title(s string) string {
+	return strings.Title(strings.Replace(s, "-", " ", -1))
 }
 
 func getLookup(field string) (lookup string, fieldName string) {
-	fieldName = template.HTMLEscapeString(field)
+	fieldName = field
 	parts := strings.Split(fieldName, ":")
 	if len(parts) > 0 {
 		lookup = fieldName
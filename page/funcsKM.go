@@ -2,7 +2,6 @@ package page
 
 import (
 	"html/template"
-	"strings"
 
 	"github.com/biz/templates"
 )
@@ -13,37 +12,25 @@ func funcsKM() {
 	templates.AddFunc("BasicRadioInputKM", BasicRadioInputKM)
 }
 
+// RadioFieldKM renders a radio without an input class
+//
+// Deprecated: use RadioFieldStyle with RadioStyleKM
 func RadioFieldKM(p *Page, field interface{}) template.HTML {
-
-	fo := convert(field)
-	value := escapeField(p, fo.Key)
-
-	checked := ""
-	if fo.InputValue == value {
-		checked = "checked"
-	}
-
-	return template.HTML(`
-		<label class="mdl-radio mdl-js-radio mdl-js-ripple-effect" for="` + fo.CssID + `">
-			<input type="radio" id="` + fo.CssID + `"  name="` + fo.Name + `" value="` + fo.InputValue + `"` + checked + ` />
-			<span class="mdl-radio__label">` + fo.Label + `</span>
-		</label>
-		`)
+	return RadioFieldStyle(p, RadioStyleKM, field)
 }
 
+// RadioInputClassKM is the same as RadioInputClass
+//
+// Deprecated: use RadioInputClass
 func RadioInputClassKM(label, name, value, class string) *FieldOptions {
-	f := BasicRadioInput(label, name, value)
+	f := BasicRadioInputKM(label, name, value)
 	f.CssClass = class
 	return f
 }
 
+// BasicRadioInputKM is the same as BasicRadioInput
+//
+// Deprecated: use BasicRadioInput
 func BasicRadioInputKM(label, name, value string) *FieldOptions {
-	return &FieldOptions{
-		Label:      label,
-		Name:       name,
-		InputValue: value,
-		CssClass:   name,
-		CssID:      strings.Replace(strings.ToLower(name+"-"+value), " ", "-", -1),
-		Key:        name,
-	}
+	return BasicRadioInput(label, name, value)
 }
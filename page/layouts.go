@@ -1,10 +1,22 @@
 package page
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+
+	"github.com/biz/templates"
+	"github.com/gin-gonic/gin"
+)
 
 var (
 	// layout that will be used in the Render function - defaults to Standard
 	Layout = Standard
+
+	// LayoutCtxKey is where a per-request layout is stored on the request's context
+	LayoutCtxKey = "Layout"
+
+	layoutsMu sync.RWMutex
+	layouts   = map[Key]LayoutTemplates{}
 )
 
 // Layout represents
@@ -20,12 +32,85 @@ const (
 	Standard Key = "standard"
 )
 
+// LayoutTemplates holds the templates that make up a layout. Each non-empty template is
+// added as a partial named after the layout key, e.g. "print.wrapper", "print.header"
+// and "print.skeleton". The wrapper is the base view used by Render.
+type LayoutTemplates struct {
+	Wrapper  string
+	Header   string
+	Skeleton string
+
+	// Theme styles the field helpers on pages rendered with the layout, DefaultTheme is used if nil
+	Theme Theme
+}
+
+// RegisterLayout adds a layout that can be selected with SetLayout or per request with SetRequestLayout
+func RegisterLayout(layout Key, t LayoutTemplates) {
+	if len(layout) == 0 {
+		panic("page: layout key is empty")
+	}
+	if len(t.Wrapper) == 0 {
+		panic(fmt.Sprintf("page: layout %v is missing a wrapper template", layout))
+	}
+
+	templates.AddPartial(layout.Suffix("wrapper"), t.Wrapper)
+	if len(t.Header) > 0 {
+		templates.AddPartial(layout.Suffix("header"), t.Header)
+	}
+	if len(t.Skeleton) > 0 {
+		templates.AddPartial(layout.Suffix("skeleton"), t.Skeleton)
+	}
+
+	layoutsMu.Lock()
+	layouts[layout] = t
+	layoutsMu.Unlock()
+}
+
+// IsLayout checks if a layout has been registered
+func IsLayout(layout Key) bool {
+	layoutsMu.RLock()
+	_, ok := layouts[layout]
+	layoutsMu.RUnlock()
+	return ok
+}
+
 // SetLayout sets which layout the render method will use
 func SetLayout(layout Key) {
-	switch layout {
-	case Standard:
-		layout = Standard
-	default:
+	if !IsLayout(layout) {
 		panic(fmt.Sprintf("invalid layout: %v", layout))
 	}
+
+	layoutsMu.Lock()
+	Layout = layout
+	layoutsMu.Unlock()
+}
+
+// defaultLayout returns the package Layout, it is guarded by the same lock as the registered layouts
+func defaultLayout() Key {
+	layoutsMu.RLock()
+	defer layoutsMu.RUnlock()
+	return Layout
+}
+
+// SetRequestLayout sets the layout the render method will use for a single request
+func SetRequestLayout(ctx *gin.Context, layout Key) {
+	if !IsLayout(layout) {
+		panic(fmt.Sprintf("invalid layout: %v", layout))
+	}
+	ctx.Set(LayoutCtxKey, layout)
+}
+
+// LayoutFromCtx returns the layout set for the request, if none has been set the package Layout is returned
+func LayoutFromCtx(ctx *gin.Context) Key {
+	v, ok := ctx.Get(LayoutCtxKey)
+	if !ok {
+		return defaultLayout()
+	}
+
+	l, ok := v.(Key)
+	if !ok {
+		panic("invalid Layout stored on context")
+	}
+
+	return l
 }
@@ -2,17 +2,51 @@ package page
 
 import "net/http"
 import "strings"
+import "sync"
 
-// DefaultMenu is used to store the menu state
-var DefaultMenu = Menu{Types: map[string][]Link{}}
+import "github.com/edataforms/pkg/session"
 
-// Menu represents the menu
+// DefaultMenu is used to store the menu state. It must not be copied once it is in use, use its address
+// where a *Menu is needed
+var DefaultMenu = Menu{
+	Types:   map[string][]Link{},
+	Parents: defaultParents(),
+}
+
+// defaultParents returns the menu types that inherit links from another menu type in every new menu
+func defaultParents() map[string][]string {
+	return map[string][]string{
+		"super-admin": {"admin"},
+	}
+}
+
+// Menu represents the menu. Each menu type is a tree of links, sub links are stored in Link.Links.
+// A Menu is safe for concurrent use, Types and Parents should only be accessed through its methods once
+// it is in use
 type Menu struct {
-	Types map[string][]Link
+	mu      sync.RWMutex
+	Types   map[string][]Link
+	Parents map[string][]string // menu types that a menu type inherits links from
+}
+
+// NewMenu creates a menu without links, e.g. for a gin engine that needs its own menu. Like the
+// DefaultMenu, "super-admin" inherits the links of "admin"
+func NewMenu() *Menu {
+	return &Menu{
+		Types:   map[string][]Link{},
+		Parents: defaultParents(),
+	}
 }
 
 // AddLink adds a links to a menu type
 func (m *Menu) AddLink(typ string, links ...Link) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Types == nil {
+		m.Types = map[string][]Link{}
+	}
+
 	if _, ok := m.Types[typ]; !ok {
 		m.Types[typ] = links
 		return
@@ -21,26 +55,85 @@ func (m *Menu) AddLink(typ string, links ...Link) {
 	m.Types[typ] = append(m.Types[typ], links...)
 }
 
+// Inherit makes a menu type include the links of its parent menu types, after its own links.
+// Parents are resolved recursively so "super-admin" can inherit from "admin" which inherits from "user"
+func (m *Menu) Inherit(typ string, parents ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Parents == nil {
+		m.Parents = map[string][]string{}
+	}
+	m.Parents[typ] = append(m.Parents[typ], parents...)
+}
+
 // Get gets menu items by section and sets the active menu item
 func (m *Menu) Get(typ string, req *http.Request) []Link {
-	links, ok := m.Types[typ]
-	if !ok {
+	m.mu.RLock()
+	links := m.links(typ, map[string]bool{})
+	m.mu.RUnlock()
+
+	if len(links) == 0 {
 		return nil
 	}
 
 	return setActiveItems(req, links)
 }
 
+// GetAllowed gets menu items by section like Get and removes the links the user is not allowed to see,
+// see Link.Allowed. The links are filtered after the lock is released so Visible funcs can use the menu
+func (m *Menu) GetAllowed(typ string, req *http.Request, s *session.Session) []Link {
+	m.mu.RLock()
+	links := m.links(typ, map[string]bool{})
+	m.mu.RUnlock()
+
+	links = filterLinks(s, links)
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	return setActiveItems(req, links)
+}
+
+// links returns the links of a menu type and the types it inherits from. Links with an href that has
+// already been added are skipped. The caller must hold the read lock
+func (m *Menu) links(typ string, seen map[string]bool) []Link {
+	if seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+
+	links := append([]Link{}, m.Types[typ]...)
+	for _, parent := range m.Parents[typ] {
+		for _, l := range m.links(parent, seen) {
+			if !hasHref(links, l.Href) {
+				links = append(links, l)
+			}
+		}
+	}
+
+	return links
+}
+
+// setActiveItems copies links and marks the link matching the request's path as active. Links whose
+// path is a parent of the request's path and links with an active descendant are marked partially active
 func setActiveItems(req *http.Request, links []Link) []Link {
 	nl := make([]Link, len(links))
 
-	// TODO: check sub links
 	pt := req.URL.Path
 	for i := 0; i < len(links); i++ {
-		nl[i] = links[i]
+		nl[i] = links[i].Clone()
+		nl[i].IsActive = false
+		nl[i].IsPartialActive = false
+
+		if len(nl[i].Links) > 0 {
+			nl[i].Links = setActiveItems(req, nl[i].Links)
+		}
+
 		if nl[i].Href == pt {
 			nl[i].IsActive = true
-		} else if strings.Contains(pt, nl[i].Href) {
+		} else if isParentPath(nl[i].Href, pt) || hasActive(nl[i].Links) {
 			nl[i].IsPartialActive = true
 		}
 	}
@@ -48,12 +141,73 @@ func setActiveItems(req *http.Request, links []Link) []Link {
 	return nl
 }
 
+// isParentPath checks if every segment of parent is a leading segment of pt, so "/a" is a parent of
+// "/a/b" but not of "/admin". The root path is not a parent of any other path
+func isParentPath(parent, pt string) bool {
+	ps := pathSegments(parent)
+	if len(ps) == 0 {
+		return false
+	}
+
+	ts := pathSegments(pt)
+	if len(ts) < len(ps) {
+		return false
+	}
+
+	for i := range ps {
+		if ps[i] != ts[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pathSegments(pt string) []string {
+	if i := strings.IndexAny(pt, "?#"); i != -1 {
+		pt = pt[:i]
+	}
+	pt = strings.Trim(pt, "/")
+	if len(pt) == 0 {
+		return nil
+	}
+	return strings.Split(pt, "/")
+}
+
+func hasActive(links []Link) bool {
+	for _, l := range links {
+		if l.IsActive || l.IsPartialActive {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHref(links []Link, href string) bool {
+	for _, l := range links {
+		if l.Href == href {
+			return true
+		}
+	}
+	return false
+}
+
 // AddMenuLink adds links to the DefaultMenu
 func AddMenuLink(typ string, links ...Link) {
 	DefaultMenu.AddLink(typ, links...)
 }
 
+// InheritMenu makes a DefaultMenu menu type include the links of its parents
+func InheritMenu(typ string, parents ...string) {
+	DefaultMenu.Inherit(typ, parents...)
+}
+
 // GetMenu gets the links from the DefaultMenu
 func GetMenu(typs string, req *http.Request) []Link {
 	return DefaultMenu.Get(typs, req)
 }
+
+// GetAllowedMenu gets the links from the DefaultMenu that the user is allowed to see
+func GetAllowedMenu(typs string, req *http.Request, s *session.Session) []Link {
+	return DefaultMenu.GetAllowed(typs, req, s)
+}
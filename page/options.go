@@ -0,0 +1,114 @@
+package page
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/edataforms/pkg/html/htmlselect"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// OptionsPath is the path of the JSON endpoint that serves the options of the registered option sources,
+// see middleware.OptionRoutes. The source's name is added to the path
+var OptionsPath = "/options/"
+
+// OptionSource provides the options of a select that are too many to render with the page, such as
+// customers or products. The options are fetched by the browser as the user types
+type OptionSource interface {
+	// Options returns up to limit options matching the query starting at offset, and whether there are
+	// more options after them
+	Options(req *http.Request, query string, offset, limit int) (options []htmlselect.Option, more bool, err error)
+
+	// Lookup returns the options of values, it is used to render the selected options of a field
+	Lookup(values ...string) ([]htmlselect.Option, error)
+}
+
+var (
+	optionSourcesMu sync.RWMutex
+	optionSources   = map[string]OptionSource{}
+)
+
+// RegisterOptionSource adds an option source that can be used by a select with SelectConfig.Source
+func RegisterOptionSource(name string, src OptionSource) {
+	if len(name) == 0 {
+		panic("page: option source name is empty")
+	}
+
+	optionSourcesMu.Lock()
+	optionSources[name] = src
+	optionSourcesMu.Unlock()
+}
+
+// GetOptionSource returns a registered option source
+func GetOptionSource(name string) (OptionSource, bool) {
+	optionSourcesMu.RLock()
+	src, ok := optionSources[name]
+	optionSourcesMu.RUnlock()
+	return src, ok
+}
+
+// RemoteSelect is used to create a select field with options fetched from a registered option source
+func RemoteSelect(p *Page, field interface{}, source string) template.HTML {
+	return Select(p, field, selectPreset(field, &SelectConfig{Source: source}))
+}
+
+// SelectSource makes a select fetch its options from a registered option source
+func SelectSource(source string, conf *SelectConfig) *SelectConfig {
+	conf.Source = source
+	return conf
+}
+
+// sourceOptions returns the options of the selected values from an option source, values without an
+// option are labelled with the value so the selection is not lost when a form is redisplayed
+func sourceOptions(source string, values []string) []htmlselect.Option {
+	var selected []string
+	for _, v := range values {
+		if len(v) > 0 {
+			selected = append(selected, v)
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	found := map[string]bool{}
+	var options []htmlselect.Option
+
+	if src, ok := GetOptionSource(source); !ok {
+		logrus.WithField("source", source).Error("page: unknown option source")
+	} else if opts, err := src.Lookup(selected...); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"source": source,
+			"error":  err,
+		}).Error("page: unable to look up options")
+	} else {
+		for _, op := range opts {
+			v, _ := op.OptionValue()
+			found[v] = true
+		}
+		options = opts
+	}
+
+	for _, v := range selected {
+		if !found[v] {
+			options = append(options, valueOption(v))
+		}
+	}
+
+	return options
+}
+
+// valueOption is an option labelled with its value
+type valueOption string
+
+func (v valueOption) OptionValue() (string, string) {
+	return string(v), string(v)
+}
+
+// sourceURL returns the url of an option source's endpoint
+func sourceURL(source string) string {
+	return OptionsPath + url.PathEscape(source)
+}
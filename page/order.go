@@ -0,0 +1,249 @@
+package page
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// OrderType converts the values of a field to values that can be compared
+type OrderType struct {
+	// Parse returns the sortable value of a field value, ok is false if the value is invalid
+	Parse func(value string) (v interface{}, ok bool)
+
+	// Compare returns a negative number if a sorts before b, a positive number if a sorts after b and 0
+	// if they are equal. a and b are values returned by Parse
+	Compare func(a, b interface{}) int
+}
+
+var (
+	// StringOrder sorts values as case insensitive strings
+	StringOrder = OrderType{
+		Parse: func(s string) (interface{}, bool) {
+			return strings.ToLower(s), true
+		},
+		Compare: func(a, b interface{}) int {
+			return strings.Compare(a.(string), b.(string))
+		},
+	}
+
+	// NumberOrder sorts values as numbers, values that are not numbers are invalid
+	NumberOrder = OrderType{
+		Parse: func(s string) (interface{}, bool) {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			return f, err == nil
+		},
+		Compare: func(a, b interface{}) int {
+			switch x, y := a.(float64), b.(float64); {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		},
+	}
+)
+
+// DateOrder sorts values as dates parsed with the first of the layouts that matches, DateLayout is used
+// if there are none
+func DateOrder(layouts ...string) OrderType {
+	if len(layouts) == 0 {
+		layouts = []string{DateLayout}
+	}
+
+	return OrderType{
+		Parse: func(s string) (interface{}, bool) {
+			for _, l := range layouts {
+				if t, err := time.Parse(l, strings.TrimSpace(s)); err == nil {
+					return t, true
+				}
+			}
+			return nil, false
+		},
+		Compare: func(a, b interface{}) int {
+			switch x, y := a.(time.Time), b.(time.Time); {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		},
+	}
+}
+
+// CustomOrder sorts values with a comparator of the raw field values
+func CustomOrder(compare func(a, b string) int) OrderType {
+	return OrderType{
+		Parse: func(s string) (interface{}, bool) {
+			return s, true
+		},
+		Compare: func(a, b interface{}) int {
+			return compare(a.(string), b.(string))
+		},
+	}
+}
+
+var (
+	orderTypesMu sync.RWMutex
+	orderTypes   = map[string]OrderType{
+		"string": StringOrder,
+		"number": NumberOrder,
+		"date":   DateOrder(),
+	}
+)
+
+// RegisterOrderType adds an order type that can be used by name with the OrderKey template func
+func RegisterOrderType(name string, t OrderType) {
+	orderTypesMu.Lock()
+	orderTypes[name] = t
+	orderTypesMu.Unlock()
+}
+
+// OrderKey is a field that group ids are sorted by, see FieldOrderBy
+type OrderKey struct {
+	Field string    // looked up as "<field>:<id>"
+	Type  OrderType // values are sorted as strings if Parse or Compare is nil
+	Desc  bool
+
+	// EmptyFirst places empty and invalid values before the other values, regardless of Desc. They are
+	// placed after them by default
+	EmptyFirst bool
+}
+
+// NewOrderKey is the OrderKey template func. typ is "string", "number", "date" or a name added with
+// RegisterOrderType. The options are "asc", "desc", "empty-first" and "empty-last", other options of a
+// "date" key are the layouts of its values
+//
+//	{{ range FieldOrderBy .Page "items" (OrderKey "due" "date" "desc" "01/02/2006") (OrderKey "name" "string") }}
+func NewOrderKey(field, typ string, options ...string) OrderKey {
+	k := OrderKey{Field: field}
+
+	var layouts []string
+	for _, o := range options {
+		switch o {
+		case "asc":
+			k.Desc = false
+		case "desc":
+			k.Desc = true
+		case "empty-first":
+			k.EmptyFirst = true
+		case "empty-last":
+			k.EmptyFirst = false
+		default:
+			layouts = append(layouts, o)
+		}
+	}
+
+	if typ == "date" {
+		k.Type = DateOrder(layouts...)
+		return k
+	}
+
+	orderTypesMu.RLock()
+	t, ok := orderTypes[typ]
+	orderTypesMu.RUnlock()
+	if !ok {
+		logrus.WithField("type", typ).Error("page: unknown order type, sorting as strings")
+		t = StringOrder
+	}
+	k.Type = t
+
+	return k
+}
+
+// orderItem is a group id with the parsed values of the order keys, nil if the value is empty or invalid
+type orderItem struct {
+	id     string
+	values []interface{}
+}
+
+type orderItems struct {
+	items []orderItem
+	keys  []OrderKey
+}
+
+func (o orderItems) Len() int      { return len(o.items) }
+func (o orderItems) Swap(i, j int) { o.items[i], o.items[j] = o.items[j], o.items[i] }
+
+func (o orderItems) Less(i, j int) bool {
+	for n, k := range o.keys {
+		a, b := o.items[i].values[n], o.items[j].values[n]
+
+		switch {
+		case a == nil && b == nil:
+			continue
+		case a == nil:
+			return k.EmptyFirst
+		case b == nil:
+			return !k.EmptyFirst
+		}
+
+		c := k.Type.Compare(a, b)
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+
+	return false
+}
+
+// FieldOrderBy returns the ids of a group sorted by the keys, a later key is used when the values of the
+// earlier keys are equal. The ids are taken from GroupValues, or from FieldGroup in id order if the group
+// has no group values, and ids with equal values keep that order
+func FieldOrderBy(p *Page, group string, keys ...OrderKey) []string {
+	ids := p.GroupValues[group]
+	if len(ids) == 0 {
+		ids = FieldGroup(p, group)
+		sort.Strings(ids)
+	}
+
+	return orderIDs(p, ids, keys)
+}
+
+// orderIDs sorts the ids of a group by the keys, see FieldOrderBy
+func orderIDs(p *Page, ids []string, keys []OrderKey) []string {
+	if len(ids) == 0 {
+		return ids
+	}
+
+	keys = append([]OrderKey{}, keys...)
+	for i, k := range keys {
+		if k.Type.Parse == nil || k.Type.Compare == nil {
+			keys[i].Type = StringOrder
+		}
+	}
+
+	items := make([]orderItem, len(ids))
+	for i, id := range ids {
+		items[i] = orderItem{id: id, values: make([]interface{}, len(keys))}
+
+		for n, k := range keys {
+			s := FieldValue(p, fmt.Sprintf("%s:%s", k.Field, id))
+			if len(strings.TrimSpace(s)) == 0 {
+				continue
+			}
+			if v, ok := k.Type.Parse(s); ok {
+				items[i].values[n] = v
+			}
+		}
+	}
+
+	sort.Stable(orderItems{items: items, keys: keys})
+
+	sorted := make([]string, len(items))
+	for i, item := range items {
+		sorted[i] = item.id
+	}
+
+	return sorted
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 
 	"github.com/biz/templates"
 	"github.com/edataforms/pkg/session"
@@ -20,47 +21,15 @@ var (
 	FormErrors   = "FormErrors"   // key used to hold form errors to be displayed to a user
 	FormValues   = "FormValues"
 	GroupValues  = "GroupValues"
+
+	FormMultiValues = "FormMultiValues" // key used to hold form fields with more than one value, e.g. a multi-select
 )
 
-// Render
+// Render renders view inside the wrapper of the request's layout
 func Render(ctx *gin.Context, view string, data interface{}) {
-	templates.MustExecute(ctx.Writer, Layout.Suffix("wrapper"), view, data)
-}
-
-/*
-type FormField struct {
-	Name       string
-	Label      string
-	Value      string              // Input values
-	Error      string              // Input error
-	InputAttrs map[string]string   // Attributes
-	InputProps map[string]struct{} // Properties
-	WrapAttrs  map[string]string   // Attributes that should be assigned to the wrapper div
-	WrapProps  map[string]string   // Properties that should be assigned to the wrapper div
-
-	Group string // for inputs that belong to an array/group
-
-	// can be used to create the html for the input
-	Func func(FormField) template.HTML
+	templates.MustExecute(ctx.Writer, LayoutFromCtx(ctx).Suffix("wrapper"), view, data)
 }
 
-// Attributes
-func (f *FormField) Attributes() template.HTMLAttr {
-	str := ""
-	for k, v := range f.Attr {
-		str += fmt.Sprintf(`%s="%v" `)
-	}
-	for k := range f.Props {
-		str += k + " "
-	}
-	if len(str) > 0 {
-		str = " " + str
-	}
-
-	return str
-}
-*/
-
 // Page represents the basic elements of an html page
 type Page struct {
 	Header                   *Header
@@ -71,18 +40,30 @@ type Page struct {
 	Scripts                  []string
 	ScriptsNoBust            []string
 	ScriptsNoBustPostScripts []string
+	AssetScripts             []Script     // attributes of the Scripts added with AddScriptTag
+	AssetLinks               []Stylesheet // integrity hashes of the Links added with AddStylesheet
 	InfoMessage              string
 	ErrorMessage             string
+	Flashes                  []Flash
 	FormErrors               map[string]string
 	FormValues               map[string]string
 	GroupValues              map[string][]string
+	FormMultiValues          map[string][]string // values of fields that can have more than one value, see FieldValues
+	Forms                    []string            // ids of the forms on the page, see FormID
 	BodyClass                string
-	//	FormFields  map[string]FormField
+	Nonce                    string               // Content-Security-Policy nonce added to inline scripts and styles
+	CSRFToken                string               // token rendered by CSRFField
+	Validator                *Validator           // used to add HTML5 validation attributes to form fields
+	RowGroups                map[string]*RowGroup // row groups rendered by RepeatGroup, see AddRowGroup
+	Layout                   Key                  // layout the page is rendered with, selects the Theme of the field helpers
+	BaseURL                  string               // scheme and host of the request, used for absolute urls, see BaseURL
 
 	FaviconHTML  template.HTML
 	CollapseMenu bool
 	GoBack       bool
 	BreadCrumbs  []BreadCrumb
+
+	session *session.Session // set by HydrateFromSession, used to restore forms added after it
 }
 
 //BreadCrumb is used to add a navigational link to the top of the content
@@ -100,12 +81,72 @@ func (p *Page) AddBreadCrumb(label, link string) *Page {
 	return p
 }
 
+// Clone returns a deep copy of p. Slices, maps, the Header, BreadCrumbs and the Validator are copied so
+// changes to the clone never write through to p, which allows a single Page to be used as a prototype for
+// every request. The Visible funcs of links, the rules of the Validator and the RowGroups are shared as
+// they are not changed after they are created, and the session p was hydrated from is not kept by the clone
+func (p *Page) Clone() *Page {
+	np := &Page{}
+	*np = *p
+	np.session = nil
+
+	if p.Validator != nil {
+		np.Validator = p.Validator.Clone()
+	}
+
+	if p.Header != nil {
+		h := *p.Header
+		h.Nav = cloneLinks(p.Header.Nav)
+		np.Header = &h
+	}
+
+	np.Nav = cloneLinks(p.Nav)
+	np.SubNav = cloneLinks(p.SubNav)
+	np.Links = cloneStrings(p.Links)
+	np.Scripts = cloneStrings(p.Scripts)
+	np.ScriptsNoBust = cloneStrings(p.ScriptsNoBust)
+	np.ScriptsNoBustPostScripts = cloneStrings(p.ScriptsNoBustPostScripts)
+	np.Forms = cloneStrings(p.Forms)
+
+	if p.AssetScripts != nil {
+		np.AssetScripts = append([]Script{}, p.AssetScripts...)
+	}
+	if p.AssetLinks != nil {
+		np.AssetLinks = append([]Stylesheet{}, p.AssetLinks...)
+	}
+	if p.Flashes != nil {
+		np.Flashes = append([]Flash{}, p.Flashes...)
+	}
+	if p.BreadCrumbs != nil {
+		np.BreadCrumbs = append([]BreadCrumb{}, p.BreadCrumbs...)
+	}
+
+	if p.FormErrors != nil {
+		np.FormErrors = merge(nil, p.FormErrors)
+	}
+	if p.FormValues != nil {
+		np.FormValues = merge(nil, p.FormValues)
+	}
+	np.GroupValues = cloneSliceMap(p.GroupValues)
+	np.FormMultiValues = cloneSliceMap(p.FormMultiValues)
+
+	if p.RowGroups != nil {
+		np.RowGroups = make(map[string]*RowGroup, len(p.RowGroups))
+		for k, g := range p.RowGroups {
+			np.RowGroups[k] = g
+		}
+	}
+
+	return np
+}
+
 // ExistingValues is used to check if the page has pre-existing form values or form errors
 func (p *Page) ExistingValues() bool {
 	return len(p.FormErrors) > 0 || len(p.FormValues) > 0
 }
 
-// AddScript adds a script to the page if it does not already exist
+// AddScript adds a script to the page if it does not already exist. Scripts found in the asset
+// manifest are rendered with their fingerprinted url instead of the cache buster, see ScriptTags
 func (p *Page) AddScript(scripts ...string) {
 	for i := 0; i < len(scripts); i++ {
 		p.Scripts = utilstrings.AddUnique(p.Scripts, scripts[i])
@@ -126,32 +167,34 @@ func (p *Page) AddScriptNoBustPostScripts(scripts ...string) {
 	}
 }
 
-// AddLink adds a link to the Page if it does not already exists
+// AddLink adds a link to the Page if it does not already exists. Links found in the asset
+// manifest are rendered with their fingerprinted url instead of the cache buster, see LinkTags
 func (p *Page) AddLink(links ...string) {
 	for i := 0; i < len(links); i++ {
 		p.Links = utilstrings.AddUnique(p.Links, links[i])
 	}
 }
 
+// HydrateFromSession restores flash messages and the state of the unscoped form and the page's Forms
+// from the user's session. If none of them have state the form stored last is restored, so a form that
+// redirects to a page with a different path is redisplayed. State stored for other forms is left in the
+// session for one more request, so the handler can still restore it with AddForm
 func (p *Page) HydrateFromSession(s *session.Session) {
-	p.FormErrors = merge(p.FormErrors, GetFormErrors(s))
-	p.FormValues = merge(p.FormValues, GetFormValues(s))
-	if len(p.ErrorMessage) == 0 {
-		p.ErrorMessage = GetErrorMessage(s)
-	}
-	if len(p.InfoMessage) == 0 {
-		p.InfoMessage = GetInfoMessage(s)
-	}
-	if p.FormValues == nil {
-		p.FormValues = map[string]string{}
+	p.session = s
+	restored := p.hydrateForm(s, "")
+	for _, id := range p.Forms {
+		restored = p.hydrateForm(s, id) || restored
 	}
-
-	if p.GroupValues == nil {
-		p.GroupValues = map[string][]string{}
-	}
-	for k, v := range GetGroupValues(s) {
-		p.GroupValues[k] = v
+	if id, ok := s.Data[LastForm].(string); ok {
+		delete(s.Data, LastForm)
+		s.ShouldSave = true
+		if !restored {
+			p.hydrateForm(s, id)
+		}
 	}
+	expireForms(s)
+	p.Flashes = append(p.Flashes, GetFlashes(s)...)
+	p.setLegacyMessages()
 }
 
 type HeaderLogo struct {
@@ -174,6 +217,54 @@ type Link struct {
 	IsPartialActive bool
 	Title           string
 	Attrs           map[string]string
+
+	// Permissions the user must have to see the link, checked with HasPermission
+	Permissions []string
+	// Visible is an optional predicate the user must pass to see the link
+	Visible func(*session.Session) bool
+}
+
+// Clone returns a deep copy of l and its sub links
+func (l Link) Clone() Link {
+	l.Links = cloneLinks(l.Links)
+	l.Permissions = cloneStrings(l.Permissions)
+	if l.Attrs != nil {
+		attrs := make(map[string]string, len(l.Attrs))
+		for k, v := range l.Attrs {
+			attrs[k] = v
+		}
+		l.Attrs = attrs
+	}
+	return l
+}
+
+func cloneLinks(links []Link) []Link {
+	if links == nil {
+		return nil
+	}
+	nl := make([]Link, len(links))
+	for i := range links {
+		nl[i] = links[i].Clone()
+	}
+	return nl
+}
+
+func cloneStrings(strs []string) []string {
+	if strs == nil {
+		return nil
+	}
+	return append([]string{}, strs...)
+}
+
+func cloneSliceMap(m map[string][]string) map[string][]string {
+	if m == nil {
+		return nil
+	}
+	nm := make(map[string][]string, len(m))
+	for k, v := range m {
+		nm[k] = cloneStrings(v)
+	}
+	return nm
 }
 
 // SetActive sets the active link
@@ -197,120 +288,73 @@ type FormError struct {
 
 // SetErrors sets an error message and form errors on to the user's session
 func SetErrors(s *session.Session, message string, errs map[string]string) {
-	s.Data[ErrorMessage] = message
-	SetFormErrors(s, errs)
-	s.ShouldSave = true
+	defaultForm(s).SetErrors(message, errs)
 }
 
 // SetInfoMessage adds an info message to the user's session
+//
+// Deprecated: use AddInfo or AddFlash, this is kept for compatibility and queues an info flash message
 func SetInfoMessage(s *session.Session, message string) {
-	s.Data[InfoMessage] = message
-	s.ShouldSave = true
+	AddFlash(s, Flash{Level: FlashInfo, Message: message})
 }
 
 func SetFormError(s *session.Session, key, value string) {
-	SetFormErrors(s, map[string]string{key: value})
+	defaultForm(s).SetFormError(key, value)
 }
 
 // setFormErrors adds form errors to the user's session
 func SetFormErrors(s *session.Session, errs map[string]string) {
-	defer func() {
-		s.ShouldSave = true
-	}()
-
-	fv, ok := s.Data[FormErrors]
-	if !ok {
-		s.Data[FormErrors] = errs
-		return
-	}
-
-	m, ok := fv.(map[string]interface{})
-	if !ok {
-		s.Data[FormErrors] = errs
-		return
-	}
-
-	for k, v := range errs {
-		m[k] = v
-	}
-
-	s.Data[FormErrors] = m
+	defaultForm(s).SetFormErrors(errs)
 }
 
 // SetFormValues adds form values to the session
 func SetFormValues(s *session.Session, values map[string]string) {
-	fv, ok := s.Data[FormValues]
-	if !ok {
-		s.Data[FormValues] = values
-		return
-	}
-
-	m, ok := fv.(map[string]string)
-	if !ok {
-		s.Data[FormValues] = values
-		return
-	}
-
-	for k, v := range values {
-		m[k] = v
-	}
-
-	s.Data[FormValues] = m
-
-	s.ShouldSave = true
+	defaultForm(s).SetFormValues(values)
 }
 
 // SetFormValue sets a single key/value form value
 func SetFormValue(s *session.Session, key, value interface{}) {
-	SetFormValues(s, map[string]string{fmt.Sprint(key): fmt.Sprint(value)})
+	defaultForm(s).SetFormValue(key, value)
 }
 
 // SetFormArrayValue sets a single key/value form value
 func SetFormArrayValue(s *session.Session, key, value, id interface{}) {
-	SetFormValues(s, map[string]string{fmt.Sprintf("%v:%v", key, id): fmt.Sprint(value)})
+	defaultForm(s).SetFormArrayValue(key, value, id)
 }
 
 // SetFormArrayError sets a single key/value form error
 func SetFormArrayError(s *session.Session, key, value, id interface{}) {
-	SetFormErrors(s, map[string]string{fmt.Sprintf("%v:%v", key, id): fmt.Sprint(value)})
+	defaultForm(s).SetFormArrayError(key, value, id)
+}
+
+// SetFormMultiValue sets all the values of a field that can have more than one value
+func SetFormMultiValue(s *session.Session, key string, values ...string) {
+	defaultForm(s).SetFormMultiValue(key, values...)
+}
+
+// SetFormURLValues adds the listed fields of posted form values to the session, see
+// FormState.SetFormURLValues
+func SetFormURLValues(s *session.Session, values url.Values, fields ...string) {
+	defaultForm(s).SetFormURLValues(values, fields...)
 }
 
 // SetGroupValue is used in conjunction with the FieldGroup template function to group
 // related fields in an array
 func SetGroupValue(s *session.Session, key string, id interface{}) {
-	str := fmt.Sprint(id)
-	SetFormValue(s, key+":"+str, str)
+	defaultForm(s).SetGroupValue(key, id)
 }
 
 // SetGroup is used to save an ordered list of keys that can be looped to look up other keys belonging
 // to the same group
 func SetGroup(s *session.Session, key string, id interface{}) {
-	s.ShouldSave = true
-
-	gv, ok := s.Data[GroupValues]
-	if !ok {
-		s.Data[GroupValues] = map[string][]string{
-			key: []string{fmt.Sprint(id)},
-		}
-		return
-	}
-	m, ok := gv.(map[string][]string)
-	if !ok {
-		s.Data[GroupValues] = map[string][]string{
-			key: []string{fmt.Sprint(id)},
-		}
-		return
-	}
-
-	m[key] = append(m[key], fmt.Sprint(id))
-
-	s.Data[GroupValues] = m
+	defaultForm(s).SetGroup(key, id)
 }
 
 // SetErrorMessage adds an error message to the user's session
+//
+// Deprecated: use AddError or AddFlash, this is kept for compatibility and queues an error flash message
 func SetErrorMessage(s *session.Session, message string) {
-	s.Data[ErrorMessage] = message
-	s.ShouldSave = true
+	AddError(s, message)
 }
 
 // GetInfoMessage gets the InfoMessage from the session. If it's not found an empty string is returned.
@@ -353,93 +397,22 @@ func GetErrorMessage(s *session.Session) string {
 }
 
 func GetGroupValues(s *session.Session) map[string][]string {
-	v, ok := s.Data[GroupValues]
-	if !ok {
-		return nil
-	}
-
-	// remove form errors from session data
-	delete(s.Data, GroupValues)
-	s.ShouldSave = true
-
-	values, ok := v.(map[string]interface{})
-	if !ok {
-		if m, ok := v.(map[string][]string); ok {
-			return m
-		}
-		logrus.WithFields(logrus.Fields{
-			"type": fmt.Sprintf("%T", v),
-		}).Error("page: invalid GroupValues stored in session")
-		return nil
-	}
-
-	m := map[string][]string{}
-
-	for k, v := range values {
-		i, ok := v.([]interface{})
-		if !ok {
-			logrus.WithFields(logrus.Fields{
-				"type": fmt.Sprintf("%T", v),
-			}).Error("page: invalid GroupValues")
-			return nil
-		}
-		for _, s := range i {
-			m[k] = append(m[k], fmt.Sprint(s))
-		}
-	}
+	return defaultForm(s).GetGroupValues()
+}
 
-	return m
+// GetFormMultiValues gets the multi-valued form fields stored in the user's session
+func GetFormMultiValues(s *session.Session) map[string][]string {
+	return defaultForm(s).GetFormMultiValues()
 }
 
 // GetFormValues gets the form values stored in the user's session
 func GetFormValues(s *session.Session) map[string]string {
-	v, ok := s.Data[FormValues]
-	if !ok {
-		return nil
-	}
-
-	// remove form errors from session data
-	delete(s.Data, FormValues)
-	s.ShouldSave = true
-
-	values, ok := v.(map[string]interface{})
-	if !ok {
-		if m, ok := v.(map[string]string); ok {
-			return m
-		}
-
-		logrus.WithFields(logrus.Fields{
-			"type": fmt.Sprintf("%T", v),
-		}).Error("page: invalid FormValues stored in session")
-		return nil
-	}
-
-	return msiTomss(values)
+	return defaultForm(s).GetFormValues()
 }
 
 // GetFormErrors gets the user's form errors from the session, if any exist they are removed from the user's session
 func GetFormErrors(s *session.Session) map[string]string {
-	v, ok := s.Data[FormErrors]
-	if !ok {
-		return nil
-	}
-
-	// remove form errors from session data
-	delete(s.Data, FormErrors)
-	s.ShouldSave = true
-
-	fe, ok := v.(map[string]interface{})
-	if !ok {
-		if m, ok := v.(map[string]string); ok {
-			return m
-		}
-		logrus.WithFields(logrus.Fields{
-			"type": fmt.Sprintf("%T", v),
-		}).Error("page: invalid FormErrors stored in session")
-		return nil
-	}
-
-	return msiTomss(fe)
+	return defaultForm(s).GetFormErrors()
 }
 
 // merge adds values from m2 to m1
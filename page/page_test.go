@@ -0,0 +1,110 @@
+package page
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func prototype() *Page {
+	return &Page{
+		Header: &Header{
+			Title: "App",
+			Nav:   []Link{{Href: "/a", Name: "A", Links: []Link{{Href: "/a/b", Name: "B"}}, Attrs: map[string]string{"x": "1"}}},
+		},
+		Nav:             []Link{{Href: "/a", Name: "A"}},
+		Links:           make([]string, 1, 10),
+		Scripts:         make([]string, 1, 10),
+		ScriptsNoBust:   make([]string, 1, 10),
+		Flashes:         make([]Flash, 1, 10),
+		FormValues:      map[string]string{"a": "1"},
+		FormErrors:      map[string]string{"a": "required"},
+		GroupValues:     map[string][]string{"g": {"1"}},
+		FormMultiValues: map[string][]string{"m": {"1", "2"}},
+		Validator:       NewValidator().Field("a", "A", Required()),
+		BreadCrumbs:     make([]BreadCrumb, 1, 10),
+	}
+}
+
+func TestCloneIsolation(t *testing.T) {
+	p := prototype()
+	want := prototype()
+
+	c := p.Clone()
+	c.Header.Title = "Changed"
+	c.Header.Nav[0].Links[0].Name = "Changed"
+	c.Header.Nav[0].Attrs["x"] = "2"
+	c.Nav[0].IsActive = true
+	c.AddScript("/script.js")
+	c.AddLink("/style.css")
+	c.AddScriptNoBust("/no-bust.js")
+	c.AddFlash(Flash{Message: "flash"})
+	c.AddBreadCrumb("Home", "/")
+	c.FormValues["a"] = "2"
+	c.FormErrors["b"] = "required"
+	c.GroupValues["g"][0] = "2"
+	c.FormMultiValues["m"] = append(c.FormMultiValues["m"], "3")
+	c.Validator.Field("a", "A", Min(1))
+
+	if !reflect.DeepEqual(p.Header, want.Header) {
+		t.Errorf("Header changed: %+v", p.Header)
+	}
+	if !reflect.DeepEqual(p.Nav, want.Nav) {
+		t.Errorf("Nav changed: %+v", p.Nav)
+	}
+	if len(p.Links) != 1 || len(p.Scripts) != 1 || len(p.ScriptsNoBust) != 1 {
+		t.Errorf("assets changed: %v %v %v", p.Links, p.Scripts, p.ScriptsNoBust)
+	}
+	if p.Links[:2][1] != "" || p.Scripts[:2][1] != "" || p.ScriptsNoBust[:2][1] != "" {
+		t.Error("clone wrote to the prototype's backing arrays")
+	}
+	if len(p.Flashes) != 1 || p.Flashes[:2][1].Message != "" {
+		t.Errorf("Flashes changed: %v", p.Flashes[:2])
+	}
+	if len(p.BreadCrumbs) != 1 || p.BreadCrumbs[:2][1].Label != "" {
+		t.Errorf("BreadCrumbs changed: %v", p.BreadCrumbs[:2])
+	}
+	if !reflect.DeepEqual(p.FormValues, want.FormValues) || !reflect.DeepEqual(p.FormErrors, want.FormErrors) {
+		t.Errorf("form state changed: %v %v", p.FormValues, p.FormErrors)
+	}
+	if !reflect.DeepEqual(p.GroupValues, want.GroupValues) || !reflect.DeepEqual(p.FormMultiValues, want.FormMultiValues) {
+		t.Errorf("group values changed: %v %v", p.GroupValues, p.FormMultiValues)
+	}
+	if got := p.Validator.Attrs("a"); !reflect.DeepEqual(got, map[string]string{"required": ""}) {
+		t.Errorf("Validator changed: %v", got)
+	}
+}
+
+// TestCloneConcurrent is run with -race to check that clones of a shared prototype don't share memory
+func TestCloneConcurrent(t *testing.T) {
+	p := prototype()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			c := p.Clone()
+			c.Header.Title = fmt.Sprint(i)
+			c.Header.Nav[0].Links[0].IsActive = true
+			c.AddScript(fmt.Sprintf("/%d.js", i))
+			c.AddLink(fmt.Sprintf("/%d.css", i))
+			c.AddFlash(Flash{Message: fmt.Sprint(i)})
+			c.AddBreadCrumb(fmt.Sprint(i), "/")
+			c.FormValues[fmt.Sprint(i)] = "1"
+			c.GroupValues["g"][0] = fmt.Sprint(i)
+			c.Validator.Field(fmt.Sprint(i), "", Required())
+
+			if c.Scripts[1] != fmt.Sprintf("/%d.js", i) {
+				t.Errorf("clone %d has script %q", i, c.Scripts[1])
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(p.Scripts) != 1 || len(p.FormValues) != 1 || p.GroupValues["g"][0] != "1" {
+		t.Errorf("prototype changed: %v %v %v", p.Scripts, p.FormValues, p.GroupValues)
+	}
+}
@@ -4,8 +4,61 @@ import "github.com/biz/templates"
 
 func partials() {
 	templates.AddPartial("messages", `
-		{{ template "infoMessage" . }}
-		{{ template "errorMessage" . }}
+{{ range $f := .Page.Messages }}
+	<div class="alert {{ $f.Class }}{{ if $f.Dismissible }} alert--dismissible{{ end }}" role="{{ if eq $f.Level "error" "warning" }}alert{{ else }}status{{ end }}">
+	{{ $f.Message }}
+	{{ if $f.Link }}
+		<a class="alert__link" href="{{ $f.Link }}">{{ if $f.LinkLabel }}{{ $f.LinkLabel }}{{ else }}{{ $f.Link }}{{ end }}</a>
+	{{ end }}
+	{{ if $f.Dismissible }}
+		<button type="button" class="alert__close" aria-label="Dismiss" data-dismiss="alert">&times;</button>
+	{{ end }}
+	</div>
+{{ end }}
+	`)
+
+	// errorSummary lists the form's errors with links to the invalid fields, it is added to a form with
+	// {{ template "errorSummary" . }}
+	templates.AddPartial("errorSummary", errorSummaryPartial)
+
+	// styles of the field helpers shared by the layouts, it is added to a layout's head with
+	// {{ template "field-styles" . }}
+	templates.AddPartial("field-styles", `
+<style{{ if .Page.Nonce }} nonce="{{ .Page.Nonce }}"{{ end }}>
+	.visually-hidden {
+		position: absolute !important;
+		width: 1px;
+		height: 1px;
+		overflow: hidden;
+		clip: rect(0 0 0 0);
+		white-space: nowrap;
+	}
+	.field-help {
+		display: block;
+		font-size: 12px;
+		color: rgba(0, 0, 0, .54);
+	}
+	.is-invalid .field-help {
+		display: none;
+	}
+	.choice-group {
+		border: 0;
+		margin: 0 0 16px;
+		padding: 0;
+	}
+	.choice-group__legend {
+		margin-bottom: 8px;
+	}
+	.choice-group--stacked > label {
+		display: block;
+		margin-bottom: 8px;
+	}
+	.choice-group--inline > label {
+		display: inline-block;
+		width: auto;
+		margin-right: 24px;
+	}
+</style>
 	`)
 
 	templates.AddPartial("errorMessage", `
@@ -31,24 +84,52 @@ func partials() {
 {{ end }}
 	`)
 
+	// template used to add the page's css links in order, see Page.LinkTags
+	templates.AddPartial("link-tags", `
+{{ range .Page.LinkTags }}
+	<link rel="stylesheet" href="{{.Href}}" type="text/css"{{ if .Integrity }} integrity="{{.Integrity}}" crossorigin="anonymous"{{ end }}{{ if $.Page.Nonce }} nonce="{{ $.Page.Nonce }}"{{ end }}>
+{{ end }}
+	`)
+
+	// template used to add the page's script tags in order, see Page.ScriptTags
+	templates.AddPartial("script-tags", `
+{{ range .Page.ScriptTags }}
+	<script src="{{.Src}}"{{ if .Module }} type="module"{{ end }}{{ if .Defer }} defer{{ end }}{{ if .Async }} async{{ end }}{{ if .Integrity }} integrity="{{.Integrity}}" crossorigin="anonymous"{{ end }}{{ if $.Page.Nonce }} nonce="{{ $.Page.Nonce }}"{{ end }}></script>
+{{ end }}
+	`)
+
 	// template used to add script tags to the page
 	templates.AddPartial("scripts", `
 {{ range .Page.Scripts }}
-	<script src="{{.}}"></script>
+	<script src="{{.}}"{{ if $.Page.Nonce }} nonce="{{ $.Page.Nonce }}"{{ end }}></script>
 {{ end }}
 	`)
 
 	// template used to add script tags to the page
 	templates.AddPartial("scripts-no-bust", `
 {{ range .Page.ScriptsNoBust }}
-	<script src="{{.}}"></script>
+	<script src="{{.}}"{{ if $.Page.Nonce }} nonce="{{ $.Page.Nonce }}"{{ end }}></script>
 {{ end }}
 	`)
 
 	// template used to add script tags to the page
 	templates.AddPartial("scripts-no-bust-post-scripts", `
 {{ range .Page.ScriptsNoBustPostScripts }}
-	<script async defer src="{{.}}"></script>
+	<script async defer src="{{.}}"{{ if $.Page.Nonce }} nonce="{{ $.Page.Nonce }}"{{ end }}></script>
 {{ end }}
 	`)
 }
+
+// errorSummaryPartial is the errorSummary partial, it is kept out of partials so the tests can render it
+const errorSummaryPartial = `
+{{ with .Page.ErrorSummary }}
+	<div class="error-summary" role="alert" tabindex="-1" aria-labelledby="error-summary-title">
+		<h2 class="error-summary__title" id="error-summary-title">There is a problem</h2>
+		<ul class="error-summary__list">
+		{{ range . }}
+			<li><a href="#{{ .ID }}">{{ .Message }}</a></li>
+		{{ end }}
+		</ul>
+	</div>
+{{ end }}
+`
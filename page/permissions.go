@@ -0,0 +1,99 @@
+package page
+
+import (
+	"net/http"
+
+	"github.com/edataforms/pkg/session"
+)
+
+// HasPermission checks if the user has a permission required by a Link. Apps that use Link.Permissions
+// must set it, by default every permission is denied
+var HasPermission = func(s *session.Session, permission string) bool {
+	return false
+}
+
+// Allowed checks if the user can see and open the link. A nil session is only allowed links without
+// permissions or a Visible predicate
+func (l Link) Allowed(s *session.Session) bool {
+	if s == nil {
+		return len(l.Permissions) == 0 && l.Visible == nil
+	}
+
+	if l.Visible != nil && !l.Visible(s) {
+		return false
+	}
+
+	for _, perm := range l.Permissions {
+		if !HasPermission(s, perm) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterLinks removes the links and sub links the user is not allowed to see
+func filterLinks(s *session.Session, links []Link) []Link {
+	var nl []Link
+	for _, l := range links {
+		if !l.Allowed(s) {
+			continue
+		}
+		l.Links = filterLinks(s, l.Links)
+		nl = append(nl, l)
+	}
+
+	return nl
+}
+
+// Allowed checks if the user can open the request's path using the rules of the links of every menu type,
+// so a user can't open a link that is left out of their menu by typing its url. The rules of every link
+// matching the path and of every link above it apply. Paths that are not in the menu are allowed
+func (m *Menu) Allowed(req *http.Request, s *session.Session) bool {
+	// the rules are checked after the lock is released so Visible funcs can use the menu
+	m.mu.RLock()
+	types := make([][]Link, 0, len(m.Types))
+	for _, links := range m.Types {
+		types = append(types, append([]Link{}, links...))
+	}
+	m.mu.RUnlock()
+
+	for _, links := range types {
+		if !allowedPath(links, req.URL.Path, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func allowedPath(links []Link, pt string, s *session.Session) bool {
+	for _, l := range links {
+		if !matchesPath(l, pt) {
+			continue
+		}
+		if !l.Allowed(s) || !allowedPath(l.Links, pt, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesPath checks if the link or one of its sub links is the path or a parent of it
+func matchesPath(l Link, pt string) bool {
+	if l.Href == pt || isParentPath(l.Href, pt) {
+		return true
+	}
+	for _, c := range l.Links {
+		if matchesPath(c, pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// MenuAllowed checks if the user can open the request's path using the DefaultMenu
+func MenuAllowed(req *http.Request, s *session.Session) bool {
+	return DefaultMenu.Allowed(req, s)
+}
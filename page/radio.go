@@ -0,0 +1,85 @@
+package page
+
+import (
+	"html/template"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// RadioStyle is a variant of the markup of a radio, registered by name with RegisterRadioStyle so apps
+// can add customer specific radios without new field helpers. Empty classes use the page's Theme
+type RadioStyle struct {
+	WrapClass  string // classes of the label that wraps the radio
+	InputClass string // classes of the input, the FieldOptions CssClass is added to them
+	LabelClass string
+
+	// NoInputClass renders the input without a class, including the FieldOptions CssClass
+	NoInputClass bool
+
+	// Template is the field template the radio is rendered with, defaults to "choice". Other templates
+	// can be defined by a Theme's Templates
+	Template string
+}
+
+// Radio styles
+const (
+	RadioStyleDefault = "default"
+	RadioStyleKM      = "km" // radios without an input class
+)
+
+var (
+	radioStylesMu sync.RWMutex
+	radioStyles   = map[string]RadioStyle{
+		RadioStyleDefault: {},
+		RadioStyleKM:      {NoInputClass: true},
+	}
+)
+
+// RegisterRadioStyle adds a radio style that can be used with RadioFieldStyle
+func RegisterRadioStyle(name string, s RadioStyle) {
+	radioStylesMu.Lock()
+	radioStyles[name] = s
+	radioStylesMu.Unlock()
+}
+
+// RadioFieldStyle renders a radio with a registered style, the default style is used if style is empty
+func RadioFieldStyle(p *Page, style string, field interface{}) template.HTML {
+	if len(style) == 0 {
+		style = RadioStyleDefault
+	}
+
+	radioStylesMu.RLock()
+	s, ok := radioStyles[style]
+	radioStylesMu.RUnlock()
+	if !ok {
+		logrus.WithField("style", style).Error("page: unknown radio style")
+	}
+
+	fo := convert(field)
+
+	// the error belongs to the radios of the field rather than a single radio, so it is left to the form
+	// or a RadioGroup to render and the radio is only marked invalid
+	f := radioField(p, fo)
+	f.Error = ""
+	if len(s.WrapClass) > 0 {
+		f.WrapClass = s.WrapClass
+	}
+	if len(s.LabelClass) > 0 {
+		f.LabelClass = s.LabelClass
+	}
+	if !s.NoInputClass {
+		f.InputClass = s.InputClass
+		if len(f.InputClass) == 0 {
+			f.InputClass = f.class(ElementRadioInput)
+		}
+		f.InputClass += " " + fo.CssClass
+	}
+
+	tmpl := s.Template
+	if len(tmpl) == 0 {
+		tmpl = "choice"
+	}
+
+	return f.render(tmpl)
+}
@@ -0,0 +1,218 @@
+package page
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// NewRowPrefix starts the ids that the browser gives rows added to a RowGroup, DecodeForm leaves the id
+// field of these rows empty
+var NewRowPrefix = "new-"
+
+// rowPlaceholder is replaced with the new row's id when a row is added in the browser
+const rowPlaceholder = "__ID__"
+
+// RowGroup defines the fields of a repeatable row, e.g. the items of an order. Rows are rendered with the
+// RepeatGroup template func, once the group is added to the page with AddRowGroup, from the ids in GroupValues and the "<field>:<id>" form values, so they can be
+// set with SetGroup and SetFormArrayValue, SetFormStruct or FormState.SetRows.
+//
+// The ids of the rows are posted in row order under the group's name and each field is posted as
+// "<field>:<id>", which is read by Decode and DecodeForm
+type RowGroup struct {
+	Name   string // name of the group, e.g. "items"
+	Fields []RowField
+	Min    int    // rows that can't be removed
+	Max    int    // rows that can be added, 0 for no limit
+	Add    string // label of the add button, defaults to "Add"
+
+	once      sync.Once
+	validator *Validator
+}
+
+// RowField is a field of a RowGroup's rows
+type RowField struct {
+	Name  string
+	Label string
+	Width string
+	Rules []Rule // checked by RowGroup.Decode for every row and added to the input as HTML5 attributes
+
+	// Render renders the field for a row, fo.Key and fo.Name are "<field>:<id>". Defaults to a TextField
+	Render func(p *Page, fo *FieldOptions) template.HTML
+}
+
+// Row is a decoded row of a RowGroup
+type Row struct {
+	ID     string
+	Values map[string]string // values by field name
+}
+
+// IsNew checks if the row was added in the browser
+func (r Row) IsNew() bool {
+	return strings.HasPrefix(r.ID, NewRowPrefix)
+}
+
+// AddRowGroup adds row groups to the page so they can be rendered with the RepeatGroup template func
+func (p *Page) AddRowGroup(groups ...*RowGroup) {
+	if p.RowGroups == nil {
+		p.RowGroups = map[string]*RowGroup{}
+	}
+
+	for _, g := range groups {
+		if len(g.Name) == 0 {
+			panic("page: row group name is empty")
+		}
+		p.RowGroups[g.Name] = g
+	}
+}
+
+// RepeatGroup is a template func that renders the rows of a RowGroup added to the page with controls to
+// add, remove and reorder rows
+func RepeatGroup(p *Page, name string) template.HTML {
+	g, ok := p.RowGroups[name]
+	if !ok {
+		logrus.WithField("group", name).Error("page: unknown row group")
+		return ""
+	}
+
+	return g.Render(p)
+}
+
+// repeatGroup is the data of the "repeat-group" template
+type repeatGroup struct {
+	*field
+	Rows     []repeatRow
+	Blank    repeatRow
+	Min      int
+	Max      int
+	Add      string
+	NewRow   string
+	Nonce    string
+	BtnClass string
+}
+
+type repeatRow struct {
+	Group  string
+	ID     string
+	Fields []template.HTML
+}
+
+// Render renders the group's rows for the page
+func (g *RowGroup) Render(p *Page) template.HTML {
+	// the group's id is its name so the errorSummary links to it
+	f := themedField(p.Theme())
+	f.ID = g.Name
+	f.Name = g.Name
+	f.Error = p.FormErrors[g.Name]
+	_, f.Invalid = p.FormErrors[g.Name]
+
+	data := &repeatGroup{
+		field:    f,
+		Min:      g.Min,
+		Max:      g.Max,
+		Add:      g.Add,
+		NewRow:   NewRowPrefix,
+		Nonce:    p.Nonce,
+		BtnClass: f.class(ElementButton),
+	}
+	if len(data.Add) == 0 {
+		data.Add = "Add"
+	}
+
+	for _, id := range p.GroupValues[g.Name] {
+		data.Rows = append(data.Rows, g.row(p, id))
+	}
+
+	// the blank row is rendered without the page's values and errors
+	data.Blank = g.row(&Page{Layout: p.Layout}, rowPlaceholder)
+
+	return renderTemplate(f.theme, "repeat-group", data)
+}
+
+func (g *RowGroup) row(p *Page, id string) repeatRow {
+	row := repeatRow{Group: g.Name, ID: id}
+
+	for _, rf := range g.Fields {
+		key := rf.Name + ":" + id
+
+		fo := LabelNameKey(rf.Label, rf.Name, key)
+		fo.Name = key
+		for _, r := range rf.Rules {
+			for k, v := range r.Attrs {
+				fo.Attr(k, v)
+			}
+		}
+
+		render := rf.Render
+		if render == nil {
+			width := rf.Width
+			render = func(p *Page, fo *FieldOptions) template.HTML {
+				return TextField(p, fo, width)
+			}
+		}
+		row.Fields = append(row.Fields, render(p, fo))
+	}
+
+	return row
+}
+
+// Decode reads the posted rows in the order they were submitted and checks the rules of the fields.
+// Errors are keyed by "<field>:<id>" so they are shown on the row's fields once saved with
+// FormState.SetErrors, an error for the number of rows is keyed by the group's name
+func (g *RowGroup) Decode(r *http.Request) ([]Row, ValidationErrors, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, nil, err
+	}
+
+	g.once.Do(func() {
+		g.validator = NewValidator()
+		for _, rf := range g.Fields {
+			g.validator.Field(rf.Name, rf.Label, rf.Rules...)
+		}
+	})
+
+	errs := ValidationErrors{}
+
+	ids := r.Form[g.Name]
+	rows := make([]Row, 0, len(ids))
+	for _, id := range ids {
+		row := Row{ID: id, Values: map[string]string{}}
+		for _, rf := range g.Fields {
+			row.Values[rf.Name] = r.Form.Get(rf.Name + ":" + id)
+		}
+		rows = append(rows, row)
+
+		for name, msg := range g.validator.Validate(row.Values) {
+			errs[name+":"+id] = msg
+		}
+	}
+
+	switch {
+	case len(rows) < g.Min:
+		errs[g.Name] = fmt.Sprintf("At least %d rows are required", g.Min)
+	case g.Max > 0 && len(rows) > g.Max:
+		errs[g.Name] = fmt.Sprintf("No more than %d rows can be added", g.Max)
+	}
+
+	return rows, errs, nil
+}
+
+// SetRows saves decoded rows to the form so they are shown again by RepeatGroup, e.g. after Decode
+// returned errors. The rows replace any rows already set for the group
+func (f *FormState) SetRows(group string, rows []Row) {
+	values := map[string]string{}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		values[group+":"+row.ID] = row.ID
+		for name, v := range row.Values {
+			values[name+":"+row.ID] = v
+		}
+		ids = append(ids, row.ID)
+	}
+	f.setGroupIDs(group, ids)
+	f.SetFormValues(values)
+}
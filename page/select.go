@@ -0,0 +1,225 @@
+package page
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/edataforms/pkg/html/htmlselect"
+)
+
+// PlaceholderMode sets how the placeholder option of a select is rendered
+type PlaceholderMode int
+
+// Placeholder modes
+const (
+	PlaceholderDisabled   PlaceholderMode = iota // the placeholder is selected when there is no value and can't be chosen
+	PlaceholderSelectable                        // the placeholder is selected when there is no value and can be chosen
+	PlaceholderIfEmpty                           // the placeholder is only added, and selected, when there is no value
+	PlaceholderNone                              // there is no placeholder
+)
+
+// SelectConfig configures a select field, see Select. The zero value renders a full width select with
+// a disabled placeholder
+type SelectConfig struct {
+	Width string // grid width, defaults to "12"
+
+	// Placeholder and PlaceholderValue are the label and value of the placeholder option, they are used
+	// as is. SelectField and the other select helpers use the field's label and "0"
+	Placeholder      string
+	PlaceholderValue string
+	PlaceholderMode  PlaceholderMode
+
+	Options  []htmlselect.Option
+	Groups   []OptionGroup
+	Disabled []string // values of options that can't be chosen
+	Hidden   []string // values of options that are hidden from the list, they are still shown if selected
+
+	// Multiple allows more than one value to be selected, the values are read with FieldValues
+	Multiple bool
+
+	// Search adds a type-ahead input that filters the options with fuse.js, which is loaded by the
+	// FormAssets middleware
+	Search bool
+
+	// Source is the name of a registered OptionSource that the options are fetched from as the user
+	// types. Only the selected options are rendered with the page
+	Source string
+}
+
+// OptionGroup is a labelled group of options rendered as an optgroup
+type OptionGroup struct {
+	Label    string
+	Options  []htmlselect.Option
+	Disabled bool
+}
+
+// fieldOptGroup is an optgroup of a select field
+type fieldOptGroup struct {
+	Label    string
+	Disabled bool
+	Options  []fieldOption
+}
+
+// Select renders a select field configured by conf, a nil conf is the same as the zero SelectConfig
+func Select(p *Page, field interface{}, conf *SelectConfig) template.HTML {
+	if conf == nil {
+		conf = &SelectConfig{}
+	}
+	fo := convert(field)
+
+	width := conf.Width
+	if len(width) == 0 {
+		width = "12"
+	}
+
+	f := selectField(p, fo, width)
+
+	values := []string{f.Value}
+	if conf.Multiple {
+		values = FieldValues(p, fo.Key)
+		f.setAttr("multiple", "")
+	}
+	empty := len(values) == 0 || (len(values) == 1 && len(values[0]) == 0)
+
+	if conf.PlaceholderMode != PlaceholderNone && (conf.PlaceholderMode != PlaceholderIfEmpty || empty) {
+		ph := fieldOption{
+			Value:    conf.PlaceholderValue,
+			Label:    conf.Placeholder,
+			Selected: empty,
+			Disabled: conf.PlaceholderMode == PlaceholderDisabled,
+		}
+		f.Options = append(f.Options, ph)
+	}
+
+	options := conf.Options
+	if len(conf.Source) > 0 {
+		options = append([]htmlselect.Option{}, options...)
+		for _, op := range sourceOptions(conf.Source, values) {
+			if v, _ := op.OptionValue(); !hasOption(options, v) {
+				options = append(options, op)
+			}
+		}
+		f.Remote = sourceURL(conf.Source)
+		f.Nonce = p.Nonce
+	}
+
+	f.Options = append(f.Options, conf.options(options, values)...)
+	for _, g := range conf.Groups {
+		f.OptGroups = append(f.OptGroups, fieldOptGroup{
+			Label:    g.Label,
+			Disabled: g.Disabled,
+			Options:  conf.options(g.Options, values),
+		})
+	}
+
+	if conf.Search && len(conf.Source) == 0 {
+		f.Search = true
+		f.Nonce = p.Nonce
+	}
+
+	return f.render("field")
+}
+
+// options converts options to field options, options with one of the values are selected
+func (c *SelectConfig) options(options []htmlselect.Option, values []string) []fieldOption {
+	fos := make([]fieldOption, len(options))
+	for i, op := range options {
+		value, label := op.OptionValue()
+		fos[i] = fieldOption{
+			Value:    value,
+			Label:    label,
+			Selected: contains(values, value),
+			Disabled: contains(c.Disabled, value),
+		}
+		fos[i].Hidden = !fos[i].Selected && contains(c.Hidden, value)
+	}
+	return fos
+}
+
+func hasOption(options []htmlselect.Option, value string) bool {
+	for _, op := range options {
+		if v, _ := op.OptionValue(); v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// selectField creates a select rendered in a select wrapper
+func selectField(p *Page, fo *FieldOptions, width string) *field {
+	f := newField(p, fo)
+	f.Control = "select"
+	f.NoLabel = true
+	f.WrapClass = f.class(ElementSelectWrap) + f.theme.Width(width)
+	f.InputClass = f.class(ElementSelectInput) + " " + fo.CssClass
+	f.addValidation(p, fo)
+
+	return f
+}
+
+// NewSelectConfig is the SelectConfig template func, the other Select template funcs are chained to it
+// with a pipeline:
+//
+//	{{ Select .Page "customer" (SelectConfig .Customers | SelectWidth "6" | SelectSearch) }}
+func NewSelectConfig(options []htmlselect.Option) *SelectConfig {
+	return &SelectConfig{Options: options}
+}
+
+// selectPreset sets the placeholder of the select helpers to the field's label with the value "0"
+func selectPreset(field interface{}, conf *SelectConfig) *SelectConfig {
+	conf.Placeholder = convert(field).Label
+	conf.PlaceholderValue = "0"
+	return conf
+}
+
+// SelectWidth sets the grid width of a select
+func SelectWidth(width string, conf *SelectConfig) *SelectConfig {
+	conf.Width = width
+	return conf
+}
+
+// SelectPlaceholder sets the placeholder of a select, mode is "disabled", "selectable", "if-empty" or "none"
+func SelectPlaceholder(label, mode string, conf *SelectConfig) *SelectConfig {
+	conf.Placeholder = label
+	switch mode {
+	case "selectable":
+		conf.PlaceholderMode = PlaceholderSelectable
+	case "if-empty":
+		conf.PlaceholderMode = PlaceholderIfEmpty
+	case "none":
+		conf.PlaceholderMode = PlaceholderNone
+	default:
+		conf.PlaceholderMode = PlaceholderDisabled
+	}
+	return conf
+}
+
+// SelectMultiple allows more than one option of a select to be selected
+func SelectMultiple(conf *SelectConfig) *SelectConfig {
+	conf.Multiple = true
+	return conf
+}
+
+// SelectSearch adds a type-ahead input to a select
+func SelectSearch(conf *SelectConfig) *SelectConfig {
+	conf.Search = true
+	return conf
+}
+
+// AddOptionGroup is the OptionGroup template func, it adds an optgroup to a select
+func AddOptionGroup(label string, options []htmlselect.Option, conf *SelectConfig) *SelectConfig {
+	conf.Groups = append(conf.Groups, OptionGroup{Label: label, Options: options})
+	return conf
+}
+
+// DisableOption disables the option of a select with the value
+func DisableOption(value interface{}, conf *SelectConfig) *SelectConfig {
+	conf.Disabled = append(conf.Disabled, fmt.Sprint(value))
+	return conf
+}
+
+// HideOption hides the option of a select with the value
+func HideOption(value interface{}, conf *SelectConfig) *SelectConfig {
+	conf.Hidden = append(conf.Hidden, fmt.Sprint(value))
+	return conf
+}
@@ -3,12 +3,13 @@ package page
 import "github.com/biz/templates"
 
 func standard() {
-	// template used as the main base view
-	templates.AddPartial("standard.wrapper", `
+	RegisterLayout(Standard, LayoutTemplates{
+		// template used as the main base view
+		Wrapper: `
 <!DOCTYPE html>
 <html>
 	<head>
-		{{ CacheLinks .Page.Links }}
+		{{ template "link-tags" . }}
 		<link rel="stylesheet" href="https://fonts.googleapis.com/icon?family=Material+Icons">
 		<link rel="stylesheet" href="https://fonts.googleapis.com/css?family=Roboto:300,400,500,700" type="text/css">
 		<meta name=viewport content="width=device-width, initial-scale=1">
@@ -22,8 +23,16 @@ func standard() {
 		<meta name="apple-mobile-web-app-capable" content="yes">
 		<meta name="apple-mobile-web-app-status-bar-style" content="black">
 
+		{{ template "field-styles" . }}
+
+		{{ if .Page.BreadCrumbs }}
+		<script type="application/ld+json"{{ if .Page.Nonce }} nonce="{{ .Page.Nonce }}"{{ end }}>
+			{{ .Page.BreadCrumbList }}
+		</script>
+		{{ end }}
+
 		{{ if .Config }}
-		<script>
+		<script{{ if .Page.Nonce }} nonce="{{ .Page.Nonce }}"{{ end }}>
 			var config = {{ Json .Config }};
 		</script>
 		{{ end }}
@@ -49,7 +58,7 @@ func standard() {
 				</div>
 			</main>
 		</div>
-		<script>
+		<script{{ if .Page.Nonce }} nonce="{{ .Page.Nonce }}"{{ end }}>
 		(function() {
 			var back = document.querySelector(".back-button");
 			if (back) {
@@ -57,11 +66,20 @@ func standard() {
 					window.history.back();
 				});
 			}
+
+			// the close buttons of dismissible messages
+			document.addEventListener("click", function(e) {
+				var btn = e.target.closest("[data-dismiss=alert]");
+				var alert = btn && btn.closest(".alert");
+				if (alert) {
+					alert.parentNode.removeChild(alert);
+				}
+			});
 		}())
 		</script>
 
 		{{ template "scripts-no-bust" . }}
-		{{ CacheScripts .Page.Scripts }}
+		{{ template "script-tags" . }}
 		{{ template "scripts-no-bust-post-scripts" . }}
 
 		{{ block "footer" . }}{{end}}
@@ -73,10 +91,10 @@ func standard() {
 		</div>
 	</body>
 </html>
-	`)
+	`,
 
-	// template will render the header and navigation
-	templates.AddPartial("standard.header", `
+		// template will render the header and navigation
+		Header: `
 {{ if .Page.Header }}
 <header class="mdl-layout__header">
 	<div class="mdl-layout__header-row">
@@ -132,20 +150,26 @@ func standard() {
 	</nav>
 </div>
 {{ end }}
-	`)
+	`,
+
+		Skeleton: `{{ template "skeleton.base" . }}`,
+		Theme:    MDL,
+	})
 
 	templates.AddPartial("skeleton.base", `
 <!DOCTYPE html>
 <html>
 	<head>
-		{{ CacheLinks .Page.Links }}
-		{{ CacheScripts .Page.Scripts }}
+		{{ template "link-tags" . }}
+		{{ template "script-tags" . }}
 		<title>{{.Page.Title}}</title>
 		<link rel="stylesheet" href="https://fonts.googleapis.com/icon?family=Material+Icons">
 		<link rel="stylesheet" href="https://fonts.googleapis.com/css?family=Roboto:300,400,500,700" type="text/css">
 		<meta name=viewport content="width=device-width, initial-scale=1">
 		<meta name="apple-mobile-web-app-capable" content="yes">
 		<meta name="apple-mobile-web-app-status-bar-style" content="black">
+
+		{{ template "field-styles" . }}
 		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
 	</head>
 	<body {{ if .Page.BodyClass }}class="{{.Page.BodyClass}}"{{end}}>
@@ -0,0 +1,190 @@
+package page
+
+import (
+	"html/template"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Element is a part of a form field or table that a Theme supplies classes for
+type Element string
+
+// Elements styled by a Theme
+const (
+	ElementInvalid        Element = "invalid"         // added to the wrapper of a field with an error
+	ElementTextWrap       Element = "text-wrap"       // wrapper of a text input or textarea with a floating label
+	ElementPlainTextWrap  Element = "plain-text-wrap" // wrapper of a text input without a label, e.g. a native date input
+	ElementTextInput      Element = "text-input"
+	ElementTextLabel      Element = "text-label"
+	ElementError          Element = "error"
+	ElementSelectWrap     Element = "select-wrap"
+	ElementSelectInput    Element = "select-input"
+	ElementRadioWrap      Element = "radio-wrap"
+	ElementRadioInput     Element = "radio-input"
+	ElementRadioLabel     Element = "radio-label"
+	ElementCheckboxWrap   Element = "checkbox-wrap"
+	ElementCheckboxInput  Element = "checkbox-input"
+	ElementCheckboxLabel  Element = "checkbox-label"
+	ElementArrayCheckWrap Element = "array-checkbox-wrap" // wrapper of a checkbox without a label, see ArrayCheckBox
+	ElementButton         Element = "button"
+	ElementHiddenLabel    Element = "hidden-label"    // label that is only read by screen readers, for fields without a visible label
+	ElementHelp           Element = "help"            // help text rendered below the input, see FieldOptions.Help
+	ElementChoices        Element = "choices"         // fieldset of a RadioGroup or CheckboxGroup
+	ElementChoicesInline  Element = "choices-inline"  // added to the fieldset of an inline group
+	ElementChoicesStacked Element = "choices-stacked" // added to the fieldset of a stacked group
+	ElementLegend         Element = "legend"
+)
+
+// Theme supplies the classes and markup of the field helpers, so they are not tied to a CSS framework.
+// A theme is selected per layout with LayoutTemplates.Theme. To move a layout to another framework
+// incrementally, embed MDL in a struct and override the methods that differ
+type Theme interface {
+	// Class returns the classes of an element
+	Class(e Element) string
+
+	// Width returns the grid classes for a field width, e.g. "6" for a field that spans 6 columns.
+	// The classes are appended to the wrapper's classes so they should start with a space
+	Width(width string) string
+
+	// Table returns the classes of a table element, "table", "td-non-numeric" or "th-non-numeric",
+	// for a table type such as "report" or "default", see CssClass
+	Table(typ, element string) string
+
+	// Templates returns field template definitions that replace the defaults in fields.go, e.g.
+	// {{ define "field" }}...{{ end }} to change the wrapper's markup. Empty keeps the defaults
+	Templates() string
+}
+
+var (
+	// MDL is the Material Design Lite theme used by the standard layout
+	MDL Theme = mdlTheme{}
+
+	// DefaultTheme is used by layouts that have not been registered with a theme
+	DefaultTheme = MDL
+)
+
+type mdlTheme struct{}
+
+var mdlClasses = map[Element]string{
+	ElementInvalid:        "is-invalid",
+	ElementTextWrap:       "mdl-textfield mdl-js-textfield mdl-textfield--floating-label",
+	ElementPlainTextWrap:  "mdl-textfield mdl-js-textfield",
+	ElementTextInput:      "mdl-textfield__input",
+	ElementTextLabel:      "mdl-textfield__label",
+	ElementError:          "mdl-textfield__error",
+	ElementSelectWrap:     "mdl-select mdl-js-select mdl-select--floating-label",
+	ElementSelectInput:    "mdl-select__input",
+	ElementRadioWrap:      "mdl-radio mdl-js-radio mdl-js-ripple-effect",
+	ElementRadioInput:     "mdl-radio__button",
+	ElementRadioLabel:     "mdl-radio__label",
+	ElementCheckboxWrap:   "mdl-checkbox mdl-js-checkbox mdl-js-ripple-effect",
+	ElementCheckboxInput:  "mdl-checkbox__input",
+	ElementCheckboxLabel:  "mdl-checkbox__label",
+	ElementArrayCheckWrap: "mdl-checkbox mdl-js-checkbox",
+	ElementButton:         "mdl-button mdl-js-button mdl-button--raised mdl-button--accent",
+	ElementHiddenLabel:    "visually-hidden",
+	ElementHelp:           "field-help",
+	ElementChoices:        "choice-group",
+	ElementChoicesInline:  "choice-group--inline",
+	ElementChoicesStacked: "choice-group--stacked",
+	ElementLegend:         "choice-group__legend",
+}
+
+func (mdlTheme) Class(e Element) string {
+	return mdlClasses[e]
+}
+
+func (mdlTheme) Width(width string) string {
+	if width == "mdl-cell" {
+		return " " + width + " "
+	}
+	if len(width) > 0 {
+		return " mdl-cell mdl-cell--" + width + "-col "
+	}
+
+	return ""
+}
+
+func (mdlTheme) Table(typ, element string) string {
+	switch typ {
+	case "report":
+		switch element {
+		case "table":
+			return " report-tbl "
+		case "td-non-numeric":
+			fallthrough
+		case "th-non-numeric":
+			return " report-tbl__text "
+		default:
+			return ""
+		}
+	case "default":
+		fallthrough
+	default:
+		switch element {
+		case "table":
+			return " edf-table mdl-data-table mdl-js-data-table "
+		case "td-non-numeric":
+			fallthrough
+		case "th-non-numeric":
+			return " mdl-data-table__cell--non-numeric "
+		default:
+			return ""
+		}
+	}
+}
+
+func (mdlTheme) Templates() string {
+	return ""
+}
+
+// LayoutTheme returns the theme of a layout, the package Layout is used if layout is empty
+func LayoutTheme(layout Key) Theme {
+	layoutsMu.RLock()
+	if len(layout) == 0 {
+		layout = Layout
+	}
+	t := layouts[layout].Theme
+	layoutsMu.RUnlock()
+
+	if t == nil {
+		return DefaultTheme
+	}
+	return t
+}
+
+// Theme returns the theme of the page's layout
+func (p *Page) Theme() Theme {
+	return LayoutTheme(p.Layout)
+}
+
+var (
+	themeTemplatesMu sync.Mutex
+	themeTemplates   = map[string]*template.Template{}
+)
+
+// fieldTemplatesFor returns the field templates with a theme's overrides, parsed once per set of overrides
+func fieldTemplatesFor(t Theme) *template.Template {
+	defs := t.Templates()
+	if len(defs) == 0 {
+		return fieldTemplates
+	}
+
+	themeTemplatesMu.Lock()
+	defer themeTemplatesMu.Unlock()
+
+	if tmpl, ok := themeTemplates[defs]; ok {
+		return tmpl
+	}
+
+	// the default templates are parsed again as html/template can't clone a template once it has been executed
+	tmpl, err := template.Must(template.New("fields").Parse(fieldTemplateSource)).Parse(defs)
+	if err != nil {
+		logrus.WithError(err).Error("page: unable to parse theme field templates")
+		tmpl = fieldTemplates
+	}
+	themeTemplates[defs] = tmpl
+
+	return tmpl
+}
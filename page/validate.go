@@ -0,0 +1,353 @@
+package page
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edataforms/pkg/session"
+)
+
+// Rule validates a single form value. Attrs holds the HTML5 attributes that enforce the same rule in the
+// browser, they are added to the input of any field helper rendered for a page with a Validator. Rules
+// are created with Check, Required and the other rule funcs, a Rule literal only adds its Attrs
+type Rule struct {
+	Attrs map[string]string
+
+	// check returns an error message if the value is invalid. lookup is used to get the value of
+	// another field in the same row
+	check func(label, value string, lookup func(string) (string, bool)) string
+
+	// run the rule even if the value is empty
+	always bool
+}
+
+// Check creates a rule from a func that returns an error message if the value is invalid
+func Check(fn func(value string) string) Rule {
+	return Rule{check: func(_, value string, _ func(string) (string, bool)) string {
+		return fn(value)
+	}}
+}
+
+// Required fails if the value is empty
+func Required() Rule {
+	return Rule{
+		Attrs:  map[string]string{"required": ""},
+		always: true,
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			if len(strings.TrimSpace(value)) == 0 {
+				return fmt.Sprintf("%s is required", label)
+			}
+			return ""
+		},
+	}
+}
+
+// Min fails if the value is not a number greater than or equal to min
+func Min(min float64) Rule {
+	return Rule{
+		Attrs: map[string]string{"min": formatFloat(min)},
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return fmt.Sprintf("%s must be a number", label)
+			}
+			if f < min {
+				return fmt.Sprintf("%s must be at least %s", label, formatFloat(min))
+			}
+			return ""
+		},
+	}
+}
+
+// Max fails if the value is not a number less than or equal to max
+func Max(max float64) Rule {
+	return Rule{
+		Attrs: map[string]string{"max": formatFloat(max)},
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return fmt.Sprintf("%s must be a number", label)
+			}
+			if f > max {
+				return fmt.Sprintf("%s must be at most %s", label, formatFloat(max))
+			}
+			return ""
+		},
+	}
+}
+
+// MinLength fails if the value has fewer than n characters
+func MinLength(n int) Rule {
+	return Rule{
+		Attrs: map[string]string{"minlength": strconv.Itoa(n)},
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			if len([]rune(value)) < n {
+				return fmt.Sprintf("%s must be at least %d characters", label, n)
+			}
+			return ""
+		},
+	}
+}
+
+// MaxLength fails if the value has more than n characters
+func MaxLength(n int) Rule {
+	return Rule{
+		Attrs: map[string]string{"maxlength": strconv.Itoa(n)},
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			if len([]rune(value)) > n {
+				return fmt.Sprintf("%s must be at most %d characters", label, n)
+			}
+			return ""
+		},
+	}
+}
+
+// Pattern fails if the whole value does not match expr. expr uses the syntax shared by Go's regexp
+// package and the pattern attribute, message is used as the error
+func Pattern(expr, message string) Rule {
+	re := regexp.MustCompile("^(?:" + expr + ")$")
+	return Rule{
+		Attrs: map[string]string{"pattern": expr, "title": message},
+		check: func(_, value string, _ func(string) (string, bool)) string {
+			if !re.MatchString(value) {
+				return message
+			}
+			return ""
+		},
+	}
+}
+
+// DateRange fails if the value is not a date in DateLayout format between from and to inclusive.
+// A zero from or to leaves that side of the range open
+func DateRange(from, to time.Time) Rule {
+	attrs := map[string]string{}
+	if !from.IsZero() {
+		attrs["min"] = from.Format(DateLayout)
+	}
+	if !to.IsZero() {
+		attrs["max"] = to.Format(DateLayout)
+	}
+
+	return Rule{
+		Attrs: attrs,
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			d, err := time.Parse(DateLayout, strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Sprintf("%s must be a valid date", label)
+			}
+			if !from.IsZero() && d.Before(from) {
+				return fmt.Sprintf("%s must be on or after %s", label, from.Format(DateLayout))
+			}
+			if !to.IsZero() && d.After(to) {
+				return fmt.Sprintf("%s must be on or before %s", label, to.Format(DateLayout))
+			}
+			return ""
+		},
+	}
+}
+
+// OneOf fails if the value is not one of values
+func OneOf(values ...string) Rule {
+	return Rule{
+		check: func(label, value string, _ func(string) (string, bool)) string {
+			if !contains(values, value) {
+				return fmt.Sprintf("%s is not a valid option", label)
+			}
+			return ""
+		},
+	}
+}
+
+// EqualTo fails if the value is different from the value of another field. For array fields the other
+// field from the same row is used, e.g. "confirm:3" is compared with "password:3"
+func EqualTo(field, label string) Rule {
+	return Rule{
+		always: true,
+		check: func(l, value string, lookup func(string) (string, bool)) string {
+			other, _ := lookup(field)
+			if value != other {
+				return fmt.Sprintf("%s must match %s", l, label)
+			}
+			return ""
+		},
+	}
+}
+
+// Validator holds the rules for the fields of a form. Rules added for a field also apply to every
+// array key of the field, so rules for "qty" validate "qty:1", "qty:2" etc.
+type Validator struct {
+	fields []validatorField
+}
+
+type validatorField struct {
+	name  string
+	label string
+	rules []Rule
+}
+
+// NewValidator creates an empty Validator
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// Clone returns a copy of v that rules can be added to without changing v. The rules themselves are
+// shared, they are not changed after they are created
+func (v *Validator) Clone() *Validator {
+	nv := &Validator{fields: make([]validatorField, len(v.fields))}
+	for i, f := range v.fields {
+		f.rules = append([]Rule{}, f.rules...)
+		nv.fields[i] = f
+	}
+	return nv
+}
+
+// Field adds rules for a field. If label is empty the title cased name is used in error messages
+func (v *Validator) Field(name, label string, rules ...Rule) *Validator {
+	if len(label) == 0 {
+		label = strings.Title(strings.Replace(name, "-", " ", -1))
+	}
+
+	for i := range v.fields {
+		if v.fields[i].name == name {
+			v.fields[i].rules = append(v.fields[i].rules, rules...)
+			return v
+		}
+	}
+
+	v.fields = append(v.fields, validatorField{name: name, label: label, rules: rules})
+	return v
+}
+
+// Validate checks values against the rules. The first failing rule of each key is returned
+func (v *Validator) Validate(values map[string]string) ValidationErrors {
+	errs := ValidationErrors{}
+
+	for _, f := range v.fields {
+		for _, key := range fieldKeys(f.name, values) {
+			id := ""
+			if i := strings.Index(key, ":"); i != -1 {
+				id = key[i:]
+			}
+
+			lookup := func(field string) (string, bool) {
+				val, ok := values[field+id]
+				return val, ok
+			}
+
+			value := values[key]
+			for _, r := range f.rules {
+				// a Rule created without one of the rule funcs only adds its attributes
+				if r.check == nil || (len(value) == 0 && !r.always) {
+					continue
+				}
+				if msg := r.check(f.label, value, lookup); len(msg) > 0 {
+					errs[key] = msg
+					break
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// ValidateStruct validates a tagged struct, see EncodeForm
+func (v *Validator) ValidateStruct(st interface{}) (ValidationErrors, error) {
+	values, _, _, err := EncodeForm(st)
+	if err != nil {
+		return nil, err
+	}
+	return v.Validate(values), nil
+}
+
+// Attrs returns the HTML5 attributes for a field's rules
+func (v *Validator) Attrs(name string) map[string]string {
+	attrs := map[string]string{}
+	for _, f := range v.fields {
+		if f.name != name {
+			continue
+		}
+		for _, r := range f.rules {
+			for k, val := range r.Attrs {
+				attrs[k] = val
+			}
+		}
+	}
+	return attrs
+}
+
+// fieldKeys returns the keys in values that belong to a field. The "<name>:<id>" keys of a field in
+// rows replace its name, otherwise the name is returned even if it has no value so missing values are
+// still checked by rules like Required
+func fieldKeys(name string, values map[string]string) []string {
+	var keys []string
+	for k := range values {
+		if strings.HasPrefix(k, name+":") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return []string{name}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidationErrors maps form keys to error messages
+type ValidationErrors map[string]string
+
+// Valid returns true if there are no errors
+func (e ValidationErrors) Valid() bool {
+	return len(e) == 0
+}
+
+// Save sets the error message and the form errors on the user's session, see SetErrors
+func (e ValidationErrors) Save(s *session.Session, message string) {
+	SetErrors(s, message, e)
+}
+
+// validationAttrs returns the HTML5 validation attributes of a field from the page's Validator
+func validationAttrs(p *Page, fo *FieldOptions) map[string]string {
+	if p.Validator == nil {
+		return nil
+	}
+
+	return p.Validator.Attrs(fo.Name)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// ErrorLink is an entry of the error summary that links to an invalid field
+type ErrorLink struct {
+	ID      string // id of the invalid field's input or of the element that groups its inputs
+	Message string
+}
+
+// ErrorSummary returns the page's form errors in key order, with the ids the field helpers give the
+// inputs, see the errorSummary partial. A field made of several inputs is linked to by the id of its
+// fieldset, RadioGroup and CheckboxGroup, its RepeatGroup or, for RadioFields and ArrayCheckBoxes, its
+// GroupError
+func (p *Page) ErrorSummary() []ErrorLink {
+	keys := make([]string, 0, len(p.FormErrors))
+	for k := range p.FormErrors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	links := make([]ErrorLink, len(keys))
+	for i, k := range keys {
+		links[i] = ErrorLink{
+			ID:      strings.Replace(k, ":", "-", -1),
+			Message: p.FormErrors[k],
+		}
+	}
+
+	return links
+}
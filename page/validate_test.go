@@ -0,0 +1,27 @@
+package page
+
+import "testing"
+
+func TestValidateAttrsOnlyRule(t *testing.T) {
+	v := NewValidator().Field("name", "Name", Rule{Attrs: map[string]string{"autocomplete": "name"}})
+
+	if errs := v.Validate(map[string]string{"name": "Jane"}); len(errs) != 0 {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
+
+func TestValidateRowKeys(t *testing.T) {
+	v := NewValidator().Field("qty", "Qty", Required())
+
+	errs := v.Validate(map[string]string{"qty": "", "qty:1": "2", "qty:2": ""})
+	if _, ok := errs["qty"]; ok {
+		t.Errorf("the name of a field in rows was checked: %v", errs)
+	}
+	if _, ok := errs["qty:2"]; !ok || len(errs) != 1 {
+		t.Errorf("errors = %v, want qty:2", errs)
+	}
+
+	if _, ok := v.Validate(map[string]string{})["qty"]; !ok {
+		t.Error("missing field was not checked")
+	}
+}